@@ -7,14 +7,22 @@
 package cmd
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/joshdk/buildversion"
+	"github.com/mattn/go-isatty"
 	"github.com/pkg/browser"
 	"github.com/spf13/cobra"
 
@@ -24,6 +32,10 @@ import (
 )
 
 type flags struct {
+	// account is the ID or name of an organization member account to log
+	// into.
+	account string
+
 	// browser indicates that the login URL should be opened with the system's
 	// default browser.
 	browser bool
@@ -32,8 +44,26 @@ type flags struct {
 	// clipboard.
 	clipboard bool
 
-	// duration is how long the AWS Console session should last before expiring.
-	duration time.Duration
+	// config is the name of the config file containing user-defined location
+	// and policy aliases.
+	config string
+
+	// consoleDomain overrides the AWS Console domain for the partition.
+	consoleDomain string
+
+	// duration is how long the AWS Console session should last before
+	// expiring, or the longest allowed duration.
+	duration durationValue
+
+	// externalID is the external ID included when assuming a role.
+	externalID string
+
+	// federationMethod is the HTTP method used when requesting a signin token
+	// from the federation endpoint.
+	federationMethod string
+
+	// federationURL overrides the federation endpoint for the partition.
+	federationURL string
 
 	// federateName is the identifier used for temporary security credentials
 	// when federating an IAM user.
@@ -42,9 +72,31 @@ type flags struct {
 	// federatePolicy is the policy ARN to attach when federating an IAM user.
 	federatePolicy string
 
+	// inputFormat is the format of the credentials read from STDIN.
+	inputFormat string
+
+	// listAccounts indicates that the organization member accounts (or the
+	// IAM Identity Center accounts and roles) should be listed instead of
+	// generating a login URL.
+	listAccounts bool
+
+	// listRegions indicates that the known regions should be listed instead
+	// of generating a login URL.
+	listRegions bool
+
 	// location is the AWS Console page to redirect to after logging in.
 	location string
 
+	// mfaCode is the MFA code included when assuming a role.
+	mfaCode string
+
+	// mfaSerial is the serial number (or ARN) of the MFA device included when
+	// assuming a role.
+	mfaSerial string
+
+	// output is the format used when printing login URLs.
+	output string
+
 	// profile is the name of profile used for retrieving credentials from the
 	// AWS cli config files.
 	profile string
@@ -55,12 +107,51 @@ type flags struct {
 	// qrSize is the width in pixels of the rendered QR code.
 	qrSize int
 
+	// roleARN is the ARN of an IAM role to assume before logging in.
+	roleARN string
+
+	// roleDuration is how long the credentials for an assumed role should
+	// last before expiring.
+	roleDuration time.Duration
+
+	// roleName is the name of the IAM role to assume when logging into an
+	// organization member account.
+	roleName string
+
+	// roleSessionName is the identifier used for the assumed role session.
+	roleSessionName string
+
 	// region is the preferred AWS Console region used when redirecting after
 	// logging in.
 	region string
 
+	// ssoLogin indicates that an SSO login should be performed if the cached
+	// SSO access token is missing or has expired.
+	ssoLogin bool
+
+	// section is the name of the INI section to read credentials from, when
+	// reading credentials from STDIN.
+	section string
+
+	// sourceProfile is the name of profile used for retrieving the
+	// credentials that are used when assuming a role.
+	sourceProfile string
+
+	// ssoSession is the name of an sso-session used for retrieving
+	// credentials from IAM Identity Center.
+	ssoSession string
+
+	// stsEndpointURL overrides the endpoint used for STS calls.
+	stsEndpointURL string
+
+	// timeout is how long each request to the federation endpoint can take.
+	timeout time.Duration
+
 	// userAgent is the user agent to use when making API calls.
 	userAgent string
+
+	// verbose enables logging of additional details to STDERR.
+	verbose bool
 }
 
 // Command returns a complete handler for the aws-console cli.
@@ -68,7 +159,7 @@ func Command() *cobra.Command { //nolint:cyclop,funlen
 	var flags flags
 
 	cmd := &cobra.Command{
-		Use:     "aws-console [profile|-]",
+		Use:     "aws-console [profile|role-arn|account-id|-]",
 		Long:    "aws-console - Generate temporary login URLs for the AWS Console",
 		Version: "-",
 
@@ -78,114 +169,255 @@ func Command() *cobra.Command { //nolint:cyclop,funlen
 		Args: cobra.MaximumNArgs(1),
 		PreRun: func(_ *cobra.Command, args []string) {
 			if len(args) >= 1 {
-				// As a convenience, determine profile name from cli args here.
-				flags.profile = args[0]
+				// As a convenience, determine profile name, role ARN, or
+				// account ID from cli args here. A profile that is named
+				// after an account ID takes precedence.
+				switch {
+				case arn.IsARN(args[0]):
+					flags.roleARN = args[0]
+				case credentials.IsAccountID(args[0]) && !credentials.ProfileExists(args[0]):
+					flags.account = args[0]
+				default:
+					flags.profile = args[0]
+				}
 			}
 		},
 
-		RunE: func(*cobra.Command, []string) error {
-			// Obtain credentials from either STDIN or a named AWS cli profile.
-			var (
-				creds  *aws.Credentials
-				err    error
-				region string
-			)
+		RunE: func(command *cobra.Command, _ []string) error {
+			if flags.output != "text" && flags.output != "json" {
+				return fmt.Errorf("unknown output format %q", flags.output)
+			}
 
-			if flags.profile == "-" {
-				// Retrieve credentials from JSON via STDIN.
-				creds, err = credentials.FromReader(os.Stdin)
-			} else {
-				// Retrieve credentials from the AWS cli config files.
-				creds, region, err = credentials.FromConfig(flags.profile)
+			if flags.federationMethod != "post" && flags.federationMethod != "get" {
+				return fmt.Errorf("unknown federation method %q", flags.federationMethod)
 			}
 
+			// Load the user-defined location and policy aliases.
+			cfg, err := loadConfig(flags.config)
 			if err != nil {
 				return err
 			}
 
-			// Set the preferred console region:
-			// - Use the value from --region if given.
-			// - Use the value from $AWS_REGION if given.
-			// - Fall back to us-east-1.
-			switch {
-			case flags.region != "":
-				region = flags.region
-			case os.Getenv("AWS_REGION") != "":
-				region = os.Getenv("AWS_REGION")
-			case region == "":
-				region = "us-east-1"
+			// List the known regions instead of generating a login URL.
+			if flags.listRegions {
+				return printRegions(os.Stdout, credentials.Regions())
 			}
 
-			partition, consoleDomain, federationURL, ok := credentials.ResolveRegionPartition(region) //nolint:varnamelen
-			if !ok {
-				return fmt.Errorf("could not determine partition for region %s", region)
+			// List the accounts and roles available through IAM Identity
+			// Center instead of generating a login URL.
+			if flags.ssoSession != "" && flags.listAccounts {
+				var roles []credentials.SSORole
+
+				err := withSSOLogin(&flags, func() (err error) {
+					roles, err = credentials.ListSSORoles(flags.ssoSession, flags.userAgent)
+
+					return err
+				})
+				if err != nil {
+					return err
+				}
+
+				return printRoles(os.Stdout, roles)
 			}
 
-			// Resolve the IAM policy ARN that will be included along with the
-			// GetFederationToken request, if a request is made.
-			federatePolicy := resolvePolicyAlias(flags.federatePolicy, partition)
+			// Obtain credentials from either STDIN, IAM Identity Center, or a
+			// named AWS cli profile.
+			src, err := obtainCredentials(command, &flags)
+			if err != nil {
+				return err
+			}
 
-			// If the named profile was configured with user credentials
-			// (opposed to a role), then the user must be federated before an
-			// AWS Console login url can be generated.
-			creds, err = credentials.FederateUser(creds, region, flags.federateName, federatePolicy, flags.duration, flags.userAgent)
+			creds := src.sets[0].Credentials
+			configProfile := settingsProfile(&flags)
+
+			// Determine the region to log into, and the AWS Console domain
+			// and federation endpoints for it.
+			endpoints, err := resolveEndpoints(&flags, src.sets, src.region, configProfile)
 			if err != nil {
 				return err
 			}
 
+			stsOptions := credentials.STSOptions{
+				Region:      endpoints.region,
+				Profile:     configProfile,
+				EndpointURL: flags.stsEndpointURL,
+				UserAgent:   flags.userAgent,
+			}
+
+			// List the organization member accounts instead of generating
+			// a login URL.
+			if flags.listAccounts {
+				accounts, err := credentials.ListAccounts(creds, endpoints.region, flags.userAgent)
+				if err != nil {
+					return err
+				}
+
+				return printAccounts(os.Stdout, accounts)
+			}
+
+			// If an account was given, then resolve the ARN of the role to
+			// assume in that account.
+			if flags.account != "" && flags.ssoSession == "" {
+				accountID, err := credentials.ResolveAccount(creds, endpoints.region, flags.account, flags.userAgent)
+				if err != nil {
+					return err
+				}
+
+				flags.roleARN = credentials.RoleARN(endpoints.partition, accountID, flags.roleName)
+			}
+
+			// If a role ARN was given, then assume that role using the
+			// previously obtained source credentials.
+			if flags.roleARN != "" {
+				creds, err = assumeRole(&flags, src, creds, stsOptions)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Resolve the IAM policy ARN that will be included along with the
+			// GetFederationToken request, if a request is made.
+			federatePolicy := resolvePolicyAlias(withAliases(policies, cfg.Policies), flags.federatePolicy, endpoints.partition)
+
 			// Resolve the given location alias into a redirect url to a
 			// service in the AWS Console.
-			location, ok := resolveLocationAlias(flags.location, consoleDomain, region)
-			if !ok {
-				return fmt.Errorf("could not resolve location %q", flags.location)
+			location, err := resolveLocationAlias(withAliases(locations, cfg.Locations), flags.location, endpoints.partition, endpoints.consoleDomain, endpoints.region)
+			if err != nil {
+				return err
 			}
 
-			// Generate a login URL for the AWS Console.
-			url, err := console.GenerateLoginURL(creds, federationURL, flags.duration, location, flags.userAgent)
+			// Construct an HTTP client for the federation requests, which
+			// honors the same CA bundle and proxy settings as the AWS cli.
+			httpClient, err := credentials.NewHTTPClient(configProfile, flags.timeout)
 			if err != nil {
-				// There is a very specific failure case where if you attempt
-				// to generate a Console login URL for an IAM Role, which
-				// itself was assumed from another IAM Role (referred to as
-				// "role chaining"), and *also* attempt to include a
-				// SessionDuration HTTP parameter, then the call will fail.
-				//
-				// Since you cannot (programmatically) determine if the
-				// incoming credentials were the product of role chaining and
-				// act accordingly, the only remediation is to retry the
-				// Console login URL generation without the duration input.
-				//
-				// This edge-case behavior is only documented in this note:
-				// | Do not use the SessionDuration HTTP parameter when you get
-				// | temporary credentials through role chaining. The operation
-				// | will fail.
-				// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_providers_enable-console-custom-url.html
-				url, err = console.GenerateLoginURL(creds, federationURL, 0, location, flags.userAgent)
+				return err
+			}
+
+			// generate federates the given credentials if needed, and then
+			// generates a login URL for the AWS Console. Also returns when
+			// the AWS Console session will end, if known.
+			generate := func(creds *aws.Credentials) (*url.URL, time.Time, error) {
+				// Fail early if the credentials have already expired.
+				if err := credentials.CheckExpiration(creds); err != nil {
+					return nil, time.Time{}, withHint(&console.ExpiredTokenError{Message: err.Error()}, &flags)
+				}
+
+				// Determine the session duration, which might be lower than
+				// the one requested.
+				duration, warning := resolveDuration(flags.duration, creds, src.chained)
+				if warning != "" {
+					fmt.Fprintln(os.Stderr, "aws-console: warning:", warning)
+				}
+
+				// A rejected session duration is only retried if it is not
+				// known whether the credentials are the product of role
+				// chaining. Federated users never are.
+				retryDuration := !src.chainingKnown && creds.SessionToken != ""
+
+				// If the named profile was configured with user credentials
+				// (opposed to a role), then the user must be federated
+				// before an AWS Console login url can be generated.
+				creds, err := credentials.FederateUser(creds, stsOptions, flags.federateName, federatePolicy, duration)
 				if err != nil {
-					return err
+					return nil, time.Time{}, withHint(err, &flags)
+				}
+
+				// Generate a login URL for the AWS Console.
+				verbosef(&flags, "requesting session duration: %s", duration)
+
+				var rejectedErr *console.SessionDurationRejectedError
+
+				loginURL, err := console.GenerateLoginURL(httpClient, creds, endpoints.federationURLs, strings.ToUpper(flags.federationMethod), duration, location, flags.userAgent)
+				if errors.As(err, &rejectedErr) && !retryDuration && !rejectedErr.MentionsDuration {
+					// The credentials are known not to be the product of role
+					// chaining, so the request was rejected because of the
+					// credentials themselves.
+					err = &console.InvalidCredentialsError{Status: rejectedErr.Status, Message: rejectedErr.Message, ClockSkew: rejectedErr.ClockSkew}
+				}
+
+				if errors.As(err, &rejectedErr) && retryDuration {
+					// There is a very specific failure case where if you
+					// attempt to generate a Console login URL for an IAM
+					// Role, which itself was assumed from another IAM Role
+					// (referred to as "role chaining"), and *also* attempt to
+					// include a SessionDuration HTTP parameter, then the call
+					// will fail.
+					//
+					// Role chaining is detected up front where possible, in
+					// which case no duration is requested for chained roles,
+					// and a rejected duration is not retried for any others.
+					// Credentials from STDIN or a credential process cannot
+					// be inspected, so the only remediation is to retry the
+					// Console login URL generation without the duration input.
+					//
+					// This edge-case behavior is only documented in this note:
+					// | Do not use the SessionDuration HTTP parameter when you
+					// | get temporary credentials through role chaining. The
+					// | operation will fail.
+					// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_providers_enable-console-custom-url.html
+					verbosef(&flags, "%v, retrying without session duration", err)
+
+					loginURL, err = console.GenerateLoginURL(httpClient, creds, endpoints.federationURLs, strings.ToUpper(flags.federationMethod), 0, location, flags.userAgent)
+					if err != nil {
+						return nil, time.Time{}, withHint(err, &flags)
+					}
+
+					return loginURL, sessionExpiration(creds, 0), nil
 				}
+
+				if err != nil {
+					return nil, time.Time{}, withHint(err, &flags)
+				}
+
+				return loginURL, sessionExpiration(creds, duration), nil
+			}
+
+			// Generate a login URL for each set of credentials, if multiple
+			// sets were read from STDIN.
+			if len(src.sets) > 1 {
+				return generateAll(os.Stdout, flags.output, src.sets, generate)
+			}
+
+			loginURL, expires, err := generate(creds)
+			if err != nil {
+				return err
+			}
+
+			// Report when the AWS Console session will end, unless it is
+			// included in a JSON record.
+			if !expires.IsZero() && flags.output != "json" {
+				fmt.Fprintf(os.Stderr, "AWS Console session expires at %s (in %s).\n", expires.Local().Format(time.RFC3339), time.Until(expires).Round(time.Second))
 			}
 
 			switch {
 			case flags.qr:
 				// Render the login url as a QR code.
-				return qr.Render(os.Stdout, url.String(), flags.qrSize)
+				return qr.Render(os.Stdout, loginURL.String(), flags.qrSize)
 			case flags.browser:
 				// Open the login url with the default browser.
-				return browser.OpenURL(url.String())
+				return browser.OpenURL(loginURL.String())
 			case flags.clipboard:
 				// Copy the login url to the system clipboard.
 				fmt.Println("Copied AWS Console login URL to clipboard.") //nolint:forbidigo
 
-				return clipboard.WriteAll(url.String())
+				return clipboard.WriteAll(loginURL.String())
+			case flags.output == "json":
+				// Print the login url as a JSON record.
+				return printResult(os.Stdout, flags.output, src.sets[0].Label, loginURL, expires, nil)
 			default:
 				// Print the login url.
-				fmt.Println(url.String()) //nolint:forbidigo
+				fmt.Println(loginURL.String()) //nolint:forbidigo
 
 				return nil
 			}
 		},
 	}
 
+	// Define -a/--account flag.
+	cmd.Flags().StringVarP(&flags.account, "account", "a",
+		"",
+		"organization account ID or name to log into")
+
 	// Define -b/--browser flag.
 	cmd.Flags().BoolVarP(&flags.browser, "browser", "b",
 		false,
@@ -196,21 +428,75 @@ func Command() *cobra.Command { //nolint:cyclop,funlen
 		false,
 		"copy login URL to clipboard")
 
+	// Define --config flag.
+	cmd.Flags().StringVar(&flags.config, "config",
+		"",
+		"config file with location and policy aliases (default ~/.config/aws-console/config.yaml)")
+
+	// Define --console-domain flag.
+	cmd.Flags().StringVar(&flags.consoleDomain, "console-domain",
+		"",
+		"override the console domain for the partition")
+
 	// Define -d/--duration flag.
-	cmd.Flags().DurationVarP(&flags.duration, "duration", "d",
-		0,
-		"session duration")
+	cmd.Flags().VarP(&flags.duration, "duration", "d",
+		"session duration, or max for the longest allowed")
+
+	// Define --external-id flag.
+	cmd.Flags().StringVar(&flags.externalID, "external-id",
+		"",
+		"external ID used when assuming a role")
+
+	// Define --federation-method flag.
+	cmd.Flags().StringVar(&flags.federationMethod, "federation-method",
+		"post",
+		"http method used when requesting a signin token (post, get)")
+
+	// Define --federation-url flag.
+	cmd.Flags().StringVar(&flags.federationURL, "federation-url",
+		"",
+		"override the federation endpoint url for the partition")
+
+	// Define -i/--input-format flag.
+	cmd.Flags().StringVarP(&flags.inputFormat, "input-format", "i",
+		"auto",
+		"format of credentials from stdin ("+strings.Join(append([]string{"auto"}, credentials.Formats()...), ", ")+")")
+
+	// Define --list-accounts flag.
+	cmd.Flags().BoolVar(&flags.listAccounts, "list-accounts",
+		false,
+		"list organization or IAM Identity Center accounts and exit")
+
+	// Define --list-regions flag.
+	cmd.Flags().BoolVar(&flags.listRegions, "list-regions",
+		false,
+		"list known regions, grouped by partition, and exit")
 
 	// Define -l/--location flag.
 	cmd.Flags().StringVarP(&flags.location, "location", "l",
 		"home",
 		"console page to redirect to after logging in")
 
+	// Define --mfa-code flag.
+	cmd.Flags().StringVar(&flags.mfaCode, "mfa-code",
+		"",
+		"MFA code used when assuming a role (prompted for if not given)")
+
+	// Define --mfa-serial flag.
+	cmd.Flags().StringVar(&flags.mfaSerial, "mfa-serial",
+		"",
+		"MFA device serial number (or ARN) used when assuming a role")
+
 	// Define -n/--name flag.
 	cmd.Flags().StringVarP(&flags.federateName, "name", "n",
 		"aws-console",
 		"name used for federated user session")
 
+	// Define -o/--output flag.
+	cmd.Flags().StringVarP(&flags.output, "output", "o",
+		"text",
+		"format used when printing login URLs (text, json)")
+
 	// Define -p/--policy flag.
 	cmd.Flags().StringVarP(&flags.federatePolicy, "policy", "p",
 		"admin",
@@ -231,11 +517,61 @@ func Command() *cobra.Command { //nolint:cyclop,funlen
 		"",
 		"preferred console region when redirecting")
 
+	// Define --role-duration flag.
+	cmd.Flags().DurationVar(&flags.roleDuration, "role-duration",
+		0,
+		"assumed role session duration")
+
+	// Define --role-name flag.
+	cmd.Flags().StringVar(&flags.roleName, "role-name",
+		"OrganizationAccountAccessRole",
+		"role name assumed in organization accounts")
+
+	// Define --role-session-name flag.
+	cmd.Flags().StringVar(&flags.roleSessionName, "role-session-name",
+		"aws-console",
+		"name used for assumed role session")
+
+	// Define --section flag.
+	cmd.Flags().StringVar(&flags.section, "section",
+		"",
+		"INI section to read credentials from stdin")
+
+	// Define --source-profile flag.
+	cmd.Flags().StringVar(&flags.sourceProfile, "source-profile",
+		"",
+		"profile used for assuming a role")
+
+	// Define --sso-login flag.
+	cmd.Flags().BoolVar(&flags.ssoLogin, "sso-login",
+		true,
+		"login to IAM Identity Center if the SSO session has expired")
+
+	// Define --sso-session flag.
+	cmd.Flags().StringVar(&flags.ssoSession, "sso-session",
+		"",
+		"sso-session used for IAM Identity Center accounts")
+
+	// Define --sts-endpoint-url flag.
+	cmd.Flags().StringVar(&flags.stsEndpointURL, "sts-endpoint-url",
+		"",
+		"endpoint url used for STS calls (default $AWS_ENDPOINT_URL_STS)")
+
+	// Define --timeout flag.
+	cmd.Flags().DurationVar(&flags.timeout, "timeout",
+		15*time.Second, //nolint:mnd
+		"timeout for each request to the federation endpoint")
+
 	// Define -A/--user-agent flag.
 	cmd.Flags().StringVarP(&flags.userAgent, "user-agent", "A",
 		buildversion.Template("joshdk/aws-console {{ .Version }}{{- if .ShortRevision }} ({{ .ShortRevision }}){{ end }}"),
 		"user agent to use for http requests")
 
+	// Define --verbose flag.
+	cmd.Flags().BoolVar(&flags.verbose, "verbose",
+		false,
+		"log additional details to stderr")
+
 	// Set a custom list of examples.
 	cmd.Example = strings.TrimRight(exampleText, "\n")
 
@@ -247,3 +583,222 @@ func Command() *cobra.Command { //nolint:cyclop,funlen
 
 	return cmd
 }
+
+// printAccounts writes a table of the given organization member accounts.
+func printAccounts(writer io.Writer, accounts []credentials.Account) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0) //nolint:mnd
+
+	fmt.Fprintln(table, "ID\tNAME\tSTATE\tEMAIL")
+
+	for _, account := range accounts {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", account.ID, account.Name, account.State, account.Email)
+	}
+
+	return table.Flush()
+}
+
+// printRegions writes a table of the given regions.
+func printRegions(writer io.Writer, regions []credentials.Region) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0) //nolint:mnd
+
+	fmt.Fprintln(table, "PARTITION\tREGION\tDESCRIPTION")
+
+	for _, region := range regions {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", region.Partition, region.ID, region.Description)
+	}
+
+	return table.Flush()
+}
+
+// printRoles writes a table of the given IAM Identity Center account and role
+// pairs.
+func printRoles(writer io.Writer, roles []credentials.SSORole) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0) //nolint:mnd
+
+	fmt.Fprintln(table, "ID\tNAME\tROLE")
+
+	for _, role := range roles {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", role.AccountID, role.AccountName, role.RoleName)
+	}
+
+	return table.Flush()
+}
+
+// withSSOLogin calls the given function, and if that fails because the cached
+// SSO access token is missing or has expired, then an SSO login is performed
+// and the function is called a second time.
+func withSSOLogin(flags *flags, fn func() error) error {
+	err := fn()
+
+	var loginErr *credentials.SSOLoginError
+	if !flags.ssoLogin || !errors.As(err, &loginErr) {
+		return err
+	}
+
+	err = credentials.LoginSSO(loginErr.Session, flags.userAgent, func(verificationURL, userCode string) error {
+		fmt.Fprintf(os.Stderr, "Confirm the SSO authorization code %s by visiting:\n%s\n", userCode, verificationURL)
+
+		switch {
+		case flags.qr && isatty.IsTerminal(os.Stderr.Fd()):
+			// Render the verification url as a QR code, but only if STDERR
+			// is a terminal, as otherwise raw PNG data would be written.
+			return qr.Render(os.Stderr, verificationURL, flags.qrSize)
+		case flags.browser:
+			// Open the verification url with the default browser.
+			return browser.OpenURL(verificationURL)
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return fn()
+}
+
+// promptMFACode prompts for, and then reads, a code for the given MFA device.
+func promptMFACode(reader io.Reader, writer io.Writer, serial string) (string, error) {
+	fmt.Fprintf(writer, "Enter MFA code for %s: ", serial)
+
+	code, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || code == "") {
+		return "", fmt.Errorf("failed to read MFA code: %w", err)
+	}
+
+	return strings.TrimSpace(code), nil
+}
+
+// verbosef prints the given formatted message to STDERR, but only if verbose
+// logging was enabled.
+func verbosef(flags *flags, format string, args ...any) {
+	if flags.verbose {
+		fmt.Fprintf(os.Stderr, "aws-console: "+format+"\n", args...)
+	}
+}
+
+// generateAll generates a login URL for each of the given sets of credentials,
+// and prints the results. Failures for individual sets of credentials are
+// included in the results, and do not stop the remaining login URLs from being
+// generated.
+func generateAll(writer io.Writer, output string, sets []credentials.LabeledCredentials, generate func(*aws.Credentials) (*url.URL, time.Time, error)) error {
+	var failures []error
+
+	for _, set := range sets {
+		loginURL, expires, err := generate(set.Credentials)
+		if err != nil {
+			failures = append(failures, err)
+		}
+
+		if err := printResult(writer, output, set.Label, loginURL, expires, err); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &generateAllError{failures: failures, total: len(sets)}
+}
+
+// generateAllError is returned when login URLs could not be generated for
+// some of the sets of credentials.
+type generateAllError struct {
+	failures []error
+	total    int
+}
+
+func (e *generateAllError) Error() string {
+	return fmt.Sprintf("failed to generate %d of %d login URLs", len(e.failures), e.total)
+}
+
+// Unwrap returns the error for the first set of credentials, but only if every
+// set of credentials failed with the same kind of error. This keeps the exit
+// code for that kind of error.
+func (e *generateAllError) Unwrap() error {
+	if len(e.failures) != e.total {
+		return nil
+	}
+
+	for _, err := range e.failures[1:] {
+		if exitCode(err) != exitCode(e.failures[0]) {
+			return nil
+		}
+	}
+
+	return e.failures[0]
+}
+
+// exitCode returns the exit code for the given error, or 1 if the error does
+// not have one.
+func exitCode(err error) int {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return 1
+}
+
+// printResult writes the result of generating a labeled login URL, as either
+// a line of text or a JSON record.
+func printResult(writer io.Writer, output, label string, loginURL *url.URL, expires time.Time, err error) error {
+	type result struct {
+		Label   string `json:"label"`
+		URL     string `json:"url,omitempty"`
+		Expires string `json:"expires,omitempty"`
+		Error   string `json:"error,omitempty"`
+		Hint    string `json:"hint,omitempty"`
+	}
+
+	var hintErr *hintError
+
+	record := result{Label: label}
+
+	switch {
+	case errors.As(err, &hintErr):
+		record.Error, record.Hint = hintErr.err.Error(), hintErr.hint
+	case err != nil:
+		record.Error = err.Error()
+	default:
+		record.URL = loginURL.String()
+	}
+
+	if !expires.IsZero() {
+		record.Expires = expires.UTC().Format(time.RFC3339)
+	}
+
+	switch output {
+	case "json":
+		return json.NewEncoder(writer).Encode(record)
+	case "text":
+		if err != nil {
+			// Errors are printed separately from the login URLs.
+			fmt.Fprintf(os.Stderr, "aws-console: %s: %s\n", label, err)
+
+			return nil
+		}
+
+		_, err := fmt.Fprintf(writer, "%s\t%s\n", label, record.URL)
+
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+}
+
+// sessionExpiration returns when an AWS Console session will end, given the
+// credentials used to create it and the requested session duration. Without a
+// duration, the session lasts as long as the credentials. Returns the zero time
+// if that is not known.
+func sessionExpiration(creds *aws.Credentials, duration time.Duration) time.Time {
+	switch {
+	case duration != 0:
+		return time.Now().Add(duration)
+	case creds.CanExpire:
+		return creds.Expires
+	default:
+		return time.Time{}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/joshdk/aws-console/console"
+	"github.com/joshdk/aws-console/credentials"
+)
+
+func TestGenerateAll(t *testing.T) {
+	t.Parallel()
+
+	var (
+		invalidErr = &hintError{err: &console.InvalidCredentialsError{Status: "400 Bad Request"}, hint: "check the credentials"}
+		expiredErr = &console.ExpiredTokenError{Message: "credentials expired"}
+		loginURL   = &url.URL{Scheme: "https", Host: "signin.aws.amazon.com", Path: "/federation"}
+	)
+
+	tests := []struct {
+		title    string
+		errs     []error
+		wantCode int
+		wantHint string
+	}{
+		{
+			title: "every set succeeds",
+			errs:  []error{nil, nil},
+		},
+		{
+			title:    "some sets fail",
+			errs:     []error{nil, invalidErr},
+			wantCode: 1,
+			wantHint: "check the credentials",
+		},
+		{
+			title:    "every set fails for the same reason",
+			errs:     []error{invalidErr, invalidErr},
+			wantCode: console.ExitCodeInvalidCredentials,
+			wantHint: "check the credentials",
+		},
+		{
+			title:    "every set fails for different reasons",
+			errs:     []error{invalidErr, expiredErr},
+			wantCode: 1,
+			wantHint: "check the credentials",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			// Fail each set of credentials with the corresponding error.
+			var (
+				sets     []credentials.LabeledCredentials
+				failures = map[*aws.Credentials]error{}
+			)
+
+			for _, err := range test.errs {
+				creds := &aws.Credentials{}
+				sets = append(sets, credentials.LabeledCredentials{Label: "set", Credentials: creds})
+				failures[creds] = err
+			}
+
+			var output bytes.Buffer
+
+			err := generateAll(&output, "json", sets, func(creds *aws.Credentials) (*url.URL, time.Time, error) {
+				if err := failures[creds]; err != nil {
+					return nil, time.Time{}, err
+				}
+
+				return loginURL, time.Time{}, nil
+			})
+
+			switch {
+			case test.wantCode == 0 && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.wantCode != 0 && err == nil:
+				t.Fatal("expected error but got none")
+			case test.wantCode != 0 && exitCode(err) != test.wantCode:
+				t.Fatalf("expected exit code %d but got %d", test.wantCode, exitCode(err))
+			}
+
+			var hint string
+
+			decoder := json.NewDecoder(&output)
+			for {
+				var record struct {
+					Error string `json:"error"`
+					Hint  string `json:"hint"`
+				}
+
+				if err := decoder.Decode(&record); err != nil {
+					break
+				}
+
+				if record.Hint != "" {
+					hint = record.Hint
+				}
+
+				if strings.Contains(record.Error, "hint:") {
+					t.Fatalf("expected error without hint but got %q", record.Error)
+				}
+			}
+
+			if hint != test.wantHint {
+				t.Fatalf("expected hint %q but got %q", test.wantHint, hint)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the format of the aws-console config file, which contains
+// user-defined location and policy aliases, like:
+//
+//	locations:
+//	  dashboard: https://{region}.{console}/cloudwatch/home?region={region}#dashboards/dashboard/Production
+//	  sfn:
+//	    url: https://{region}.{console}/states/home?region={region}#/statemachines
+//	    partitions: [aws, aws-us-gov]
+//	policies:
+//	  s3: arn:{partition}:iam::aws:policy/AmazonS3ReadOnlyAccess
+type config struct {
+	Locations map[string]location `yaml:"locations"`
+	Policies  map[string]string   `yaml:"policies"`
+}
+
+// UnmarshalYAML decodes a location alias, which is either a URL, or a mapping
+// with the url, partitions, and global keys.
+func (l *location) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = location{url: node.Value}
+
+		return nil
+	}
+
+	var raw struct {
+		URL        string   `yaml:"url"`
+		Partitions []string `yaml:"partitions"`
+		Global     bool     `yaml:"global"`
+	}
+
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	if raw.URL == "" {
+		return fmt.Errorf("line %d: location is missing a url", node.Line)
+	}
+
+	*l = location{url: raw.URL, partitions: raw.Partitions, global: raw.Global}
+
+	return nil
+}
+
+// defaultConfigFilename returns the name of the default config file, which is
+// $XDG_CONFIG_HOME/aws-console/config.yaml, or otherwise
+// ~/.config/aws-console/config.yaml.
+func defaultConfigFilename() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "aws-console", "config.yaml")
+}
+
+// loadConfig reads the config file with the given name, or the default config
+// file if no name is given. The default config file is optional, so an empty
+// config is returned if it does not exist.
+func loadConfig(filename string) (*config, error) {
+	optional := filename == ""
+	if optional {
+		filename = defaultConfigFilename()
+	}
+
+	body, err := os.ReadFile(filename)
+	if err != nil {
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			return &config{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg config
+	if err := yaml.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", filename, err)
+	}
+
+	return &cfg, nil
+}
+
+// withAliases returns a copy of the given built-in aliases, with the given
+// user-defined aliases added, overriding built-in aliases of the same name.
+func withAliases[V any](builtin, user map[string]V) map[string]V {
+	aliases := maps.Clone(builtin)
+	maps.Copy(aliases, user)
+
+	return aliases
+}
@@ -0,0 +1,220 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	// Point the default config file at an empty directory.
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	write := func(name, body string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		return filename
+	}
+
+	valid := write("valid.yaml", `
+locations:
+  dashboard: https://{region}.{console}/cloudwatch/home?region={region}#dashboards
+  sfn:
+    url: https://{region}.{console}/states/home?region={region}#/statemachines
+    partitions: [aws, aws-us-gov]
+    global: true
+policies:
+  s3: arn:{partition}:iam::aws:policy/AmazonS3ReadOnlyAccess
+`)
+	invalid := write("invalid.yaml", "locations: [")
+	missingURL := write("missing-url.yaml", "locations:\n  sfn:\n    global: true\n")
+
+	tests := []struct {
+		title         string
+		filename      string
+		wantLocations map[string]location
+		wantPolicies  map[string]string
+		wantErr       string
+	}{
+		{
+			title: "missing default config",
+		},
+		{
+			title:    "missing config",
+			filename: filepath.Join(dir, "missing.yaml"),
+			wantErr:  "failed to read config:",
+		},
+		{
+			title:    "valid config",
+			filename: valid,
+			wantLocations: map[string]location{
+				"dashboard": {url: "https://{region}.{console}/cloudwatch/home?region={region}#dashboards"},
+				"sfn":       {url: "https://{region}.{console}/states/home?region={region}#/statemachines", partitions: []string{"aws", "aws-us-gov"}, global: true},
+			},
+			wantPolicies: map[string]string{
+				"s3": "arn:{partition}:iam::aws:policy/AmazonS3ReadOnlyAccess",
+			},
+		},
+		{
+			title:    "invalid config",
+			filename: invalid,
+			wantErr:  "failed to parse config " + invalid + ":",
+		},
+		{
+			title:    "location without url",
+			filename: missingURL,
+			wantErr:  "failed to parse config " + missingURL + ": line 3: location is missing a url",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			cfg, err := loadConfig(test.filename)
+
+			switch {
+			case test.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), test.wantErr)):
+				t.Fatalf("expected error %q but got %v", test.wantErr, err)
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.wantErr != "":
+				return
+			}
+
+			if !equalLocations(cfg.Locations, test.wantLocations) {
+				t.Fatalf("expected locations %+v but got %+v", test.wantLocations, cfg.Locations)
+			}
+
+			if len(cfg.Policies) != len(test.wantPolicies) {
+				t.Fatalf("expected policies %v but got %v", test.wantPolicies, cfg.Policies)
+			}
+
+			for name, policy := range test.wantPolicies {
+				if cfg.Policies[name] != policy {
+					t.Fatalf("expected policies %v but got %v", test.wantPolicies, cfg.Policies)
+				}
+			}
+		})
+	}
+}
+
+func TestLocationUnmarshalYAML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		title   string
+		body    string
+		want    location
+		wantErr string
+	}{
+		{
+			title: "url",
+			body:  "https://{region}.{console}/ec2/home",
+			want:  location{url: "https://{region}.{console}/ec2/home"},
+		},
+		{
+			title: "mapping",
+			body:  "url: https://{region}.{console}/iam/home\npartitions: [aws]\nglobal: true\n",
+			want:  location{url: "https://{region}.{console}/iam/home", partitions: []string{"aws"}, global: true},
+		},
+		{
+			title: "mapping with only url",
+			body:  "url: https://{region}.{console}/ec2/home\n",
+			want:  location{url: "https://{region}.{console}/ec2/home"},
+		},
+		{
+			title:   "mapping without url",
+			body:    "partitions: [aws]\n",
+			wantErr: "line 1: location is missing a url",
+		},
+		{
+			title:   "sequence",
+			body:    "[https://example.com]",
+			wantErr: "yaml: unmarshal errors:",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			var got location
+
+			err := yaml.Unmarshal([]byte(test.body), &got)
+
+			switch {
+			case test.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), test.wantErr)):
+				t.Fatalf("expected error %q but got %v", test.wantErr, err)
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.wantErr == "" && !equalLocation(got, test.want):
+				t.Fatalf("expected location %+v but got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestWithAliases(t *testing.T) {
+	t.Parallel()
+
+	builtin := map[string]string{
+		"admin": "arn:{partition}:iam::aws:policy/AdministratorAccess",
+		"ro":    "arn:{partition}:iam::aws:policy/ReadOnlyAccess",
+	}
+
+	user := map[string]string{
+		"ro": "arn:{partition}:iam::123456789012:policy/ReadOnly",
+		"s3": "arn:{partition}:iam::aws:policy/AmazonS3ReadOnlyAccess",
+	}
+
+	aliases := withAliases(builtin, user)
+
+	// User-defined aliases are added, and override built-in aliases.
+	for name, want := range map[string]string{
+		"admin": builtin["admin"],
+		"ro":    user["ro"],
+		"s3":    user["s3"],
+	} {
+		if aliases[name] != want {
+			t.Fatalf("expected alias %q to be %q but got %q", name, want, aliases[name])
+		}
+	}
+
+	// The built-in aliases are left unchanged.
+	if len(builtin) != 2 || builtin["ro"] != "arn:{partition}:iam::aws:policy/ReadOnlyAccess" {
+		t.Fatalf("built-in aliases were modified: %v", builtin)
+	}
+}
+
+// equalLocations reports whether the given location aliases are the same.
+func equalLocations(a, b map[string]location) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for name, value := range a {
+		if other, ok := b[name]; !ok || !equalLocation(value, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equalLocation reports whether the given locations are the same.
+func equalLocation(a, b location) bool {
+	return a.url == b.url && a.global == b.global && slices.Equal(a.partitions, b.partitions)
+}
@@ -0,0 +1,173 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/joshdk/aws-console/credentials"
+)
+
+const (
+	// maxConsoleDuration is the maximum value of the SessionDuration
+	// parameter, and the longest that any AWS Console session can last.
+	// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_providers_enable-console-custom-url.html.
+	maxConsoleDuration = 12 * time.Hour
+
+	// maxChainedDuration is the longest that a session for a role assumed by
+	// role chaining can last. The AWS Console session for such a role lasts
+	// until the role session expires.
+	// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_terms-and-concepts.html#iam-term-role-chaining.
+	maxChainedDuration = time.Hour
+
+	// minConsoleDuration is the minimum value of the SessionDuration
+	// parameter.
+	minConsoleDuration = 15 * time.Minute
+)
+
+// durationValue is a pflag.Value for a session duration, which accepts either
+// a time.Duration or the special value "max".
+type durationValue struct {
+	// value is the requested session duration, or zero if none was given.
+	value time.Duration
+
+	// max indicates that the longest allowed session duration was requested.
+	max bool
+}
+
+func (d *durationValue) Set(value string) error {
+	if value == "max" {
+		d.value, d.max = 0, true
+
+		return nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	d.value, d.max = duration, false
+
+	return nil
+}
+
+func (d *durationValue) String() string {
+	if d.max {
+		return "max"
+	}
+
+	return d.value.String()
+}
+
+func (*durationValue) Type() string {
+	return "duration"
+}
+
+// resolveDuration returns the session duration to request for the given
+// credentials. When the longest allowed session duration was requested, that
+// is 12 hours, but never beyond the expiration of the credentials. A requested
+// duration that exceeds those limits is lowered, and a warning describing why
+// is also returned. No duration can be requested for roles assumed by role
+// chaining, so the default duration is always used for them.
+func resolveDuration(requested durationValue, creds *aws.Credentials, chained bool) (time.Duration, string) {
+	// Use the default duration, as none was requested.
+	if requested.value == 0 && !requested.max {
+		return 0, ""
+	}
+
+	// Use the default duration, which lasts until the credentials expire, as
+	// the session duration cannot be given for roles assumed by role chaining.
+	if chained {
+		if requested.max {
+			return 0, ""
+		}
+
+		return 0, fmt.Sprintf("a session duration cannot be requested for roles assumed by role chaining, ignoring requested duration of %s", requested.value)
+	}
+
+	limit, reason := maxConsoleDuration, "the maximum console session duration"
+
+	// The session cannot outlive the credentials used to create it.
+	if creds.CanExpire {
+		if remaining := time.Until(creds.Expires).Truncate(time.Minute); remaining < limit {
+			limit, reason = remaining, "the remaining credential lifetime"
+		}
+	}
+
+	// Use the default duration, as the credentials will expire before the
+	// shortest allowed session duration.
+	if limit < minConsoleDuration {
+		return 0, ""
+	}
+
+	switch {
+	case requested.max:
+		return limit, ""
+	case requested.value > limit:
+		return limit, fmt.Sprintf("requested duration of %s exceeds %s, using %s instead", requested.value, reason, limit)
+	default:
+		return requested.value, ""
+	}
+}
+
+// assumeLongestRole assumes a role using the longest role session duration,
+// to the hour, that is allowed by the role's maximum session duration and is
+// no longer than the given limit. The maximum session duration of a role can
+// only be read with iam:GetRole, which the caller is often not allowed to do,
+// so instead the longest allowed duration is searched for, using assume to
+// assume the role with a given duration. The limit is tried first, as that is
+// the most likely duration to be allowed if a longer session was configured.
+//
+// An MFA code can only be used once, so when search is false, only the limit
+// and then 1 hour are tried, as the code is not used up by a rejected
+// duration.
+func assumeLongestRole(limit time.Duration, search bool, assume func(time.Duration) (*aws.Credentials, error)) (*aws.Credentials, error) {
+	creds, err := assume(limit)
+	if err == nil || !credentials.IsDurationExceeded(err) || limit <= time.Hour {
+		return creds, err
+	}
+
+	if !search {
+		return assume(time.Hour)
+	}
+
+	// Every role allows sessions of at least 1 hour, so search for the
+	// longest allowed duration between that and the limit.
+	var (
+		low, high = 1, int(limit/time.Hour) - 1
+		best      *aws.Credentials
+	)
+
+	for low < high {
+		middle := (low + high + 1) / 2 //nolint:mnd
+
+		creds, err := assume(time.Duration(middle) * time.Hour)
+
+		switch {
+		case err == nil:
+			low, best = middle, creds
+		case credentials.IsDurationExceeded(err):
+			high = middle - 1
+		case best != nil:
+			// Keep the longest role session obtained so far.
+			return best, nil
+		default:
+			return nil, err
+		}
+	}
+
+	// The role was already assumed using the longest allowed duration,
+	// unless only the shortest duration is allowed.
+	if best != nil {
+		return best, nil
+	}
+
+	return assume(time.Duration(low) * time.Hour)
+}
@@ -0,0 +1,236 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+)
+
+func TestResolveDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		title       string
+		requested   durationValue
+		expires     time.Duration
+		chained     bool
+		want        time.Duration
+		wantWarning bool
+	}{
+		{
+			title: "no duration",
+		},
+		{
+			title:     "requested duration",
+			requested: durationValue{value: 2 * time.Hour},
+			want:      2 * time.Hour,
+		},
+		{
+			title:     "requested duration within credential lifetime",
+			requested: durationValue{value: 2 * time.Hour},
+			expires:   3 * time.Hour,
+			want:      2 * time.Hour,
+		},
+		{
+			title:       "requested duration beyond credential lifetime",
+			requested:   durationValue{value: 2 * time.Hour},
+			expires:     time.Hour + 30*time.Second,
+			want:        time.Hour,
+			wantWarning: true,
+		},
+		{
+			title:       "requested duration beyond maximum",
+			requested:   durationValue{value: 24 * time.Hour},
+			want:        maxConsoleDuration,
+			wantWarning: true,
+		},
+		{
+			title:     "max duration",
+			requested: durationValue{max: true},
+			want:      maxConsoleDuration,
+		},
+		{
+			title:     "max duration within credential lifetime",
+			requested: durationValue{max: true},
+			expires:   45*time.Minute + 30*time.Second,
+			want:      45 * time.Minute,
+		},
+		{
+			title:     "credentials expire before minimum duration",
+			requested: durationValue{max: true},
+			expires:   10 * time.Minute,
+		},
+		{
+			title:       "requested duration with role chaining",
+			requested:   durationValue{value: 2 * time.Hour},
+			chained:     true,
+			wantWarning: true,
+		},
+		{
+			title:     "max duration with role chaining",
+			requested: durationValue{max: true},
+			chained:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			var creds aws.Credentials
+			if test.expires != 0 {
+				creds.CanExpire, creds.Expires = true, time.Now().Add(test.expires)
+			}
+
+			duration, warning := resolveDuration(test.requested, &creds, test.chained)
+
+			switch {
+			case duration != test.want:
+				t.Fatalf("expected duration %s but got %s", test.want, duration)
+			case test.wantWarning && warning == "":
+				t.Fatal("expected a warning but got none")
+			case !test.wantWarning && warning != "":
+				t.Fatalf("unexpected warning: %s", warning)
+			}
+		})
+	}
+}
+
+func TestAssumeLongestRole(t *testing.T) {
+	t.Parallel()
+
+	exceededErr := &smithy.GenericAPIError{
+		Code:    "ValidationError",
+		Message: "The requested DurationSeconds exceeds the MaxSessionDuration set for this role.",
+	}
+
+	for allowed := time.Hour; allowed <= maxConsoleDuration; allowed += time.Hour {
+		t.Run(allowed.String(), func(t *testing.T) {
+			t.Parallel()
+
+			creds, err := assumeLongestRole(maxConsoleDuration, true, func(duration time.Duration) (*aws.Credentials, error) {
+				if duration > allowed {
+					return nil, exceededErr
+				}
+
+				return &aws.Credentials{CanExpire: true, Expires: time.Unix(0, 0).Add(duration)}, nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := creds.Expires.Sub(time.Unix(0, 0)); got != allowed {
+				t.Fatalf("expected duration %s but got %s", allowed, got)
+			}
+		})
+	}
+
+	t.Run("other error", func(t *testing.T) {
+		t.Parallel()
+
+		deniedErr := errors.New("access denied")
+
+		_, err := assumeLongestRole(maxConsoleDuration, true, func(duration time.Duration) (*aws.Credentials, error) {
+			if duration == maxConsoleDuration {
+				return nil, exceededErr
+			}
+
+			return nil, deniedErr
+		})
+		if !errors.Is(err, deniedErr) {
+			t.Fatalf("expected error %v but got %v", deniedErr, err)
+		}
+	})
+
+	t.Run("other error after a longer session", func(t *testing.T) {
+		t.Parallel()
+
+		// Like an MFA code that was already used by a successful attempt.
+		var used bool
+
+		creds, err := assumeLongestRole(maxConsoleDuration, true, func(duration time.Duration) (*aws.Credentials, error) {
+			switch {
+			case duration > 4*time.Hour:
+				return nil, exceededErr
+			case used:
+				return nil, errors.New("MultiFactorAuthentication failed")
+			default:
+				used = true
+
+				return &aws.Credentials{CanExpire: true, Expires: time.Unix(0, 0).Add(duration)}, nil
+			}
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if creds == nil {
+			t.Fatal("expected credentials but got none")
+		}
+	})
+
+	t.Run("without search", func(t *testing.T) {
+		t.Parallel()
+
+		var attempts []time.Duration
+
+		creds, err := assumeLongestRole(maxConsoleDuration, false, func(duration time.Duration) (*aws.Credentials, error) {
+			attempts = append(attempts, duration)
+			if duration > 4*time.Hour {
+				return nil, exceededErr
+			}
+
+			return &aws.Credentials{}, nil
+		})
+
+		switch {
+		case err != nil:
+			t.Fatalf("unexpected error: %v", err)
+		case creds == nil:
+			t.Fatal("expected credentials but got none")
+		case len(attempts) != 2 || attempts[1] != time.Hour:
+			t.Fatalf("expected attempts of 12h and 1h but got %v", attempts)
+		}
+	})
+}
+
+func TestDurationValueSet(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    durationValue
+		wantErr bool
+	}{
+		{value: "max", want: durationValue{max: true}},
+		{value: "90m", want: durationValue{value: 90 * time.Minute}},
+		{value: "forever", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			t.Parallel()
+
+			var got durationValue
+
+			err := got.Set(test.value)
+
+			switch {
+			case test.wantErr && err == nil:
+				t.Fatal("expected error but got none")
+			case !test.wantErr && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case got != test.want:
+				t.Fatalf("expected %+v but got %+v", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/joshdk/aws-console/console"
+)
+
+// maxClockSkew is how far the local clock can be off before requests signed
+// with AWS credentials are rejected.
+// See https://docs.aws.amazon.com/IAM/latest/UserGuide/signature-v4-troubleshooting.html.
+const maxClockSkew = 5 * time.Minute
+
+// hintError is an error along with a hint describing how it might be resolved.
+type hintError struct {
+	err  error
+	hint string
+}
+
+func (e *hintError) Error() string {
+	return e.err.Error() + "\nhint: " + e.hint
+}
+
+func (e *hintError) Unwrap() error {
+	return e.err
+}
+
+// withHint returns the given error along with a hint describing how it might
+// be resolved, if one is known.
+func withHint(err error, flags *flags) error {
+	if hint := errorHint(err, flags); hint != "" {
+		return &hintError{err: err, hint: hint}
+	}
+
+	return err
+}
+
+// errorHint returns a short explanation of the given error, along with the
+// next steps for resolving it. Returns an empty string if the error is not
+// known.
+func errorHint(err error, flags *flags) string { //nolint:cyclop
+	if err == nil {
+		return ""
+	}
+
+	// A clock that is off is the likely cause of any failure.
+	if skew := clockSkew(err); skew.Abs() >= maxClockSkew {
+		return describeClockSkew(skew)
+	}
+
+	var (
+		apiErr         smithy.APIError
+		opErr          *smithy.OperationError
+		expiredErr     *console.ExpiredTokenError
+		invalidErr     *console.InvalidCredentialsError
+		rejectedErr    *console.SessionDurationRejectedError
+		unavailableErr *console.FederationUnavailableError
+	)
+
+	// Find the operation that failed, if this was an AWS API error.
+	var operation string
+	if errors.As(err, &opErr) {
+		operation = opErr.OperationName
+	}
+
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErrorHint(apiErr, operation, flags)
+	case errors.As(err, &expiredErr):
+		return "the credentials have expired, obtain new credentials and try again"
+	case errors.As(err, &invalidErr):
+		return "the credentials were rejected, check that they were copied correctly, have not been revoked, and are for the same partition as --region"
+	case errors.As(err, &rejectedErr):
+		return "try again without --duration, or with a shorter one"
+	case errors.As(err, &unavailableErr):
+		return "check your network connection and proxy settings, or try again later"
+	default:
+		return ""
+	}
+}
+
+// apiErrorHint returns a short explanation of the given AWS API error, which
+// was returned by the named operation.
+func apiErrorHint(apiErr smithy.APIError, operation string, flags *flags) string {
+	switch apiErr.ErrorCode() {
+	case "ExpiredToken", "ExpiredTokenException":
+		return "the session token has expired, obtain new credentials and try again"
+	case "InvalidClientTokenId", "UnrecognizedClientException":
+		return "the access key ID is not recognized, check that the credentials are still active and that --region is in the same partition as the credentials"
+	case "SignatureDoesNotMatch":
+		if strings.Contains(apiErr.ErrorMessage(), "Signature expired") {
+			return "the request was signed too long ago, check that your clock is correct"
+		}
+
+		return "the secret access key does not match the access key ID, check that the credentials were copied correctly"
+	case "RegionDisabledException":
+		return "STS is not activated in this region, activate it in the IAM account settings or use --region to choose another"
+	case "AccessDenied", "AccessDeniedException":
+		switch {
+		case operation == "GetFederationToken":
+			return "this IAM user is not allowed to call GetFederationToken, which is the case if the user requires MFA; assume a role instead, using --mfa-serial"
+		case operation == "AssumeRole" && strings.Contains(apiErr.ErrorMessage(), "MultiFactorAuthentication"):
+			return "the MFA code was rejected, wait for the next code and try again, as each code can only be used once"
+		case operation == "AssumeRole" && flags.mfaSerial == "":
+			return "the role might require MFA, use --mfa-serial, or an external ID, use --external-id"
+		case operation == "AssumeRole":
+			return "check that the role exists, and that its trust policy allows the caller to assume it"
+		}
+	}
+
+	return ""
+}
+
+// clockSkew returns how far the local clock is ahead of AWS, according to the
+// Date header of the response that caused the given error. Returns zero if
+// that is not known.
+func clockSkew(err error) time.Duration {
+	if skew := console.ClockSkew(err); skew != 0 {
+		return skew
+	}
+
+	var respErr *smithyhttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.Response == nil {
+		return 0
+	}
+
+	date, parseErr := http.ParseTime(respErr.Response.Header.Get("Date"))
+	if parseErr != nil {
+		return 0
+	}
+
+	return time.Since(date)
+}
+
+// describeClockSkew returns a hint describing how far the local clock is off.
+func describeClockSkew(skew time.Duration) string {
+	direction := "ahead of"
+	if skew < 0 {
+		direction = "behind"
+	}
+
+	return fmt.Sprintf("your clock is %d minutes %s AWS, synchronize it and try again", int(skew.Abs().Round(time.Minute).Minutes()), direction)
+}
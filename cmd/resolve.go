@@ -0,0 +1,323 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+
+	"github.com/joshdk/aws-console/console"
+	"github.com/joshdk/aws-console/credentials"
+)
+
+// source is the credentials obtained for generating login URLs.
+type source struct {
+	// sets is each labeled set of credentials. There is more than one set only
+	// if they were read from STDIN.
+	sets []credentials.LabeledCredentials
+
+	// region is the region set in the profile, if there is one.
+	region string
+
+	// chained indicates that the credentials are known to be the product of
+	// role chaining.
+	chained bool
+
+	// chainingKnown indicates that it could be determined whether the
+	// credentials are the product of role chaining.
+	chainingKnown bool
+}
+
+// endpoints is the partition and region that login URLs are generated for,
+// along with the AWS Console domain and federation endpoints for them.
+type endpoints struct {
+	partition      string
+	region         string
+	consoleDomain  string
+	federationURLs []string
+}
+
+// obtainCredentials obtains credentials from either STDIN, IAM Identity
+// Center, or a named AWS cli profile. Also tracks if the credentials are known
+// to be for a role assumed by role chaining, and if that could be determined
+// at all.
+func obtainCredentials(command *cobra.Command, flags *flags) (*source, error) {
+	var (
+		src   source
+		creds *aws.Credentials
+	)
+
+	err := withSSOLogin(flags, func() (err error) {
+		switch {
+		case flags.profile == "-":
+			// Retrieve one or more sets of credentials via STDIN.
+			src.sets, err = credentials.FromReaderAll(os.Stdin, flags.inputFormat, flags.section)
+		case flags.ssoSession != "":
+			// Retrieve credentials from IAM Identity Center. The role name is
+			// only used if it was explicitly given, since the default is meant
+			// for organization member accounts.
+			roleName := ""
+			if command.Flags().Changed("role-name") {
+				roleName = flags.roleName
+			}
+
+			creds, err = credentials.FromSSO(flags.ssoSession, flags.account, roleName, flags.userAgent)
+
+			// Credentials from IAM Identity Center are never the product of
+			// role chaining.
+			src.chainingKnown = true
+		case flags.roleARN != "", flags.account != "", flags.listAccounts:
+			// Retrieve the source credentials for assuming a role from the
+			// AWS cli config files. A profile name given as an argument is
+			// used if --source-profile was not.
+			sourceProfile := flags.sourceProfile
+			if sourceProfile == "" {
+				sourceProfile = flags.profile
+			}
+
+			creds, src.region, err = credentials.FromConfig(sourceProfile)
+		default:
+			// Retrieve credentials from the AWS cli config files.
+			creds, src.region, err = credentials.FromConfig(flags.profile)
+			src.chained = credentials.IsChainedProfile(flags.profile)
+			src.chainingKnown = src.chained
+			verbosef(flags, "profile uses role chaining: %t", src.chained)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Login URLs for multiple sets of credentials are only printed, and roles
+	// are not assumed using each of them.
+	if len(src.sets) > 1 {
+		switch {
+		case flags.browser, flags.clipboard, flags.qr:
+			return nil, errors.New("--browser, --clipboard, and --qr cannot be used with multiple sets of credentials")
+		case flags.account != "", flags.listAccounts:
+			return nil, errors.New("--account and --list-accounts cannot be used with multiple sets of credentials")
+		}
+	}
+
+	// Label the single set of credentials, if they were not read from STDIN.
+	if len(src.sets) == 0 {
+		label := flags.profile
+		if flags.roleARN != "" {
+			label = flags.roleARN
+		}
+
+		src.sets = []credentials.LabeledCredentials{{Label: label, Credentials: creds}}
+	}
+
+	return &src, nil
+}
+
+// settingsProfile returns the profile whose settings apply to the STS and
+// federation requests. Credentials from STDIN or IAM Identity Center are not
+// tied to a profile, so the default profile is used for them.
+func settingsProfile(flags *flags) string {
+	switch {
+	case flags.profile == "-", flags.ssoSession != "":
+		return ""
+	case flags.sourceProfile != "":
+		return flags.sourceProfile
+	default:
+		return flags.profile
+	}
+}
+
+// resolveEndpoints determines the region to log into, and the partition, AWS
+// Console domain, and federation endpoints for it. The given sets of
+// credentials are checked to be for the same partition, and the given region
+// from the profile is used if no other region was given.
+func resolveEndpoints(flags *flags, sets []credentials.LabeledCredentials, region, configProfile string) (*endpoints, error) {
+	// Determine the partition of the credentials from the ARN of the role
+	// being assumed, either the one given or the one from the profile, if
+	// there is one.
+	credsPartition := credentials.ARNPartition(flags.roleARN)
+	if credsPartition == "" && flags.profile != "-" && flags.ssoSession == "" {
+		credsPartition = credentials.ProfilePartition(configProfile)
+	}
+
+	// Set the preferred console region:
+	// - Use the value from --region if given.
+	// - Use the value from $AWS_REGION if given.
+	// - Use the value from the profile if set.
+	// - Fall back to the default region for the partition of the
+	//   credentials.
+	switch {
+	case flags.region != "":
+		region = flags.region
+	case os.Getenv("AWS_REGION") != "":
+		region = os.Getenv("AWS_REGION")
+	}
+
+	// The console domain and federation URL are overridden using the values
+	// from --console-domain and --federation-url, or the profile's
+	// console_domain and federation_url settings, if given.
+	settings := credentials.ProfileSettings(configProfile)
+
+	consoleDomain := flags.consoleDomain
+	if consoleDomain == "" {
+		consoleDomain = settings["console_domain"]
+	}
+
+	federationURL := flags.federationURL
+	if federationURL == "" {
+		federationURL = settings["federation_url"]
+	}
+
+	// Reject regions that are not known, unless the console domain or
+	// federation URL were overridden, as is the case for partitions that are
+	// not known. This happens before any STS calls are made in that region.
+	overridden := consoleDomain != "" || federationURL != ""
+	if region != "" && !overridden {
+		if err := credentials.ValidateRegion(region); err != nil {
+			return nil, &console.UnknownPartitionError{Region: region, Err: err}
+		}
+	}
+
+	// Otherwise, determine the partition of the credentials from the caller
+	// identity, so that it can be checked against the region.
+	if credsPartition == "" {
+		var err error
+
+		credsPartition, err = callerPartition(flags, sets, credentials.STSOptions{
+			Region:      region,
+			Profile:     configProfile,
+			EndpointURL: flags.stsEndpointURL,
+			UserAgent:   flags.userAgent,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if region == "" {
+		region = credentials.DefaultRegion(credsPartition)
+		verbosef(flags, "using default region %s", region)
+	}
+
+	partition, defaultDomain, federationURLs, _ := credentials.ResolveRegionPartition(region)
+
+	// Credentials are only valid within their own partition.
+	if credsPartition != "" && credsPartition != partition && !overridden {
+		return nil, fmt.Errorf("region %s is in the %s partition, but the credentials are for the %s partition", region, partition, credsPartition)
+	}
+
+	if consoleDomain == "" {
+		consoleDomain = defaultDomain
+	}
+
+	if federationURL != "" {
+		federationURLs = []string{federationURL}
+	}
+
+	if consoleDomain == "" || len(federationURLs) == 0 {
+		return nil, &console.UnknownPartitionError{Region: region}
+	}
+
+	verbosef(flags, "federation endpoints: %s", strings.Join(federationURLs, ", "))
+
+	return &endpoints{
+		partition:      partition,
+		region:         region,
+		consoleDomain:  consoleDomain,
+		federationURLs: federationURLs,
+	}, nil
+}
+
+// callerPartition determines the partition of each of the given sets of
+// credentials from the caller identity. Sets whose partition could not be
+// determined are skipped. Login URLs are generated for a single region, so an
+// error is returned if the sets are for different partitions.
+func callerPartition(flags *flags, sets []credentials.LabeledCredentials, options credentials.STSOptions) (string, error) {
+	var partition string
+
+	for _, set := range sets {
+		setPartition, err := credentials.CallerPartition(set.Credentials, options)
+		if err != nil {
+			verbosef(flags, "could not determine partition of credentials: %v", err)
+
+			continue
+		}
+
+		if partition != "" && setPartition != partition {
+			return "", fmt.Errorf("credentials %s are for the %s partition, but other credentials are for the %s partition", set.Label, setPartition, partition)
+		}
+
+		partition = setPartition
+	}
+
+	return partition, nil
+}
+
+// assumeRole assumes the role given with --role-arn (or resolved from
+// --account) using the given source credentials. Whether the role is assumed
+// by role chaining is recorded in the given source.
+func assumeRole(flags *flags, src *source, creds *aws.Credentials, stsOptions credentials.STSOptions) (*aws.Credentials, error) {
+	if err := credentials.CheckExpiration(creds); err != nil {
+		return nil, withHint(&console.ExpiredTokenError{Message: err.Error()}, flags)
+	}
+
+	// Roles assumed using credentials for another role are the product of
+	// role chaining. If that cannot be determined, then a rejected session
+	// duration is handled later on instead.
+	chained, err := credentials.IsRoleSession(creds, stsOptions)
+	if err != nil {
+		verbosef(flags, "could not determine caller identity: %v", err)
+	}
+
+	src.chained, src.chainingKnown = chained, err == nil
+
+	verbosef(flags, "role assumed using role chaining: %t", chained)
+
+	// Prompt for an MFA code if an MFA device was given without one. STDIN
+	// cannot be used if credentials were read from it.
+	if flags.mfaSerial != "" && flags.mfaCode == "" {
+		if flags.profile == "-" {
+			return nil, errors.New("--mfa-code must be given when reading credentials from stdin")
+		}
+
+		flags.mfaCode, err = promptMFACode(os.Stdin, os.Stderr, flags.mfaSerial)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sourceCreds := creds
+
+	assume := func(roleDuration time.Duration) (*aws.Credentials, error) {
+		verbosef(flags, "requesting role session duration: %s", roleDuration)
+
+		return credentials.AssumeRole(sourceCreds, stsOptions, flags.roleARN, flags.roleSessionName, flags.externalID, flags.mfaSerial, flags.mfaCode, roleDuration)
+	}
+
+	// Request the longest allowed role session when the longest allowed
+	// console session was requested.
+	switch {
+	case flags.duration.max && flags.roleDuration == 0 && chained:
+		creds, err = assume(maxChainedDuration)
+	case flags.duration.max && flags.roleDuration == 0:
+		creds, err = assumeLongestRole(maxConsoleDuration, flags.mfaSerial == "", assume)
+	default:
+		creds, err = assume(flags.roleDuration)
+	}
+
+	if err != nil {
+		return nil, withHint(err, flags)
+	}
+
+	return creds, nil
+}
@@ -5,58 +5,99 @@
 
 package cmd
 
-import "strings"
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/joshdk/aws-console/credentials"
+)
+
+// location is a page in the AWS Console.
+type location struct {
+	// url is the template for the URL of the page.
+	url string
+
+	// partitions are the partitions that the page is available in, or nil
+	// if it is available in every partition.
+	partitions []string
+
+	// global indicates that the page is for a global service, which is
+	// located in the home region of each partition, instead of the console
+	// region.
+	global bool
+}
+
+// commercial are the partitions that have billing, and global services like
+// CloudFront, which are not available in the other partitions.
+var commercial = []string{"aws", "aws-cn"} //nolint:gochecknoglobals
 
 // locations is a list of aliases that can be resolved to URLs in the AWS
 // Console. Used for quickly redirecting the user to the desired service after
 // logging in.
-var locations = map[string]string{ //nolint:gochecknoglobals
-	"account":    "https://{region}.{console}/billing/home?region={region}#/account",
-	"billing":    "https://{region}.{console}/costmanagement/home?region={region}#/home",
-	"cloudfront": "https://{region}.{console}/cloudfront/v4/home?region={region}#/distributions",
-	"cloudtrail": "https://{region}.{console}/cloudtrailv2/home?region={region}#/dashboard",
-	"cloudwatch": "https://{region}.{console}/cloudwatch/home?region={region}#home:",
-	"console":    "https://{region}.{console}/console/home?region={region}",
-	"ec2":        "https://{region}.{console}/ec2/home?region={region}#Instances:",
-	"ecr":        "https://{region}.{console}/ecr/private-registry/repositories?region={region}",
-	"ecs":        "https://{region}.{console}/ecs/v2/clusters?region={region}",
-	"eip":        "https://{region}.{console}/vpcconsole/home?region={region}#Addresses:",
-	"eks":        "https://{region}.{console}/eks/clusters?region={region}",
-	"groups":     "https://{region}.{console}/iam/home?region={region}#/groups",
-	"home":       "https://{region}.{console}/console/home?region={region}",
-	"iam":        "https://{region}.{console}/iam/home?region={region}#/home",
-	"kms":        "https://{region}.{console}/kms/home?region={region}#/kms/home",
-	"org":        "https://{region}.{console}/organizations/v2/home?region={region}",
-	"policies":   "https://{region}.{console}/iam/home?region={region}#/policies",
-	"r53":        "https://{region}.{console}/route53/v2/hostedzones?region={region}",
-	"rds":        "https://{region}.{console}/rds/home?region={region}#databases:",
-	"roles":      "https://{region}.{console}/iam/home?region={region}#/roles",
-	"s3":         "https://{region}.{console}/s3/buckets?region={region}",
-	"support":    "https://support.{console}/support/home?region={region}#/case/history",
-	"users":      "https://{region}.{console}/iam/home?region={region}#/users",
-	"vpc":        "https://{region}.{console}/vpcconsole/home?region={region}#vpcs:",
-	"vpn":        "https://{region}.{console}/vpcconsole/home?region={region}#ClientVPNEndpoints:",
+var locations = map[string]location{ //nolint:gochecknoglobals
+	"account":    {url: "https://{region}.{console}/billing/home?region={region}#/account", partitions: commercial, global: true},
+	"billing":    {url: "https://{region}.{console}/costmanagement/home?region={region}#/home", partitions: commercial, global: true},
+	"cloudfront": {url: "https://{region}.{console}/cloudfront/v4/home?region={region}#/distributions", partitions: commercial, global: true},
+	"cloudtrail": {url: "https://{region}.{console}/cloudtrailv2/home?region={region}#/dashboard"},
+	"cloudwatch": {url: "https://{region}.{console}/cloudwatch/home?region={region}#home:"},
+	"console":    {url: "https://{region}.{console}/console/home?region={region}"},
+	"ec2":        {url: "https://{region}.{console}/ec2/home?region={region}#Instances:"},
+	"ecr":        {url: "https://{region}.{console}/ecr/private-registry/repositories?region={region}"},
+	"ecs":        {url: "https://{region}.{console}/ecs/v2/clusters?region={region}"},
+	"eip":        {url: "https://{region}.{console}/vpcconsole/home?region={region}#Addresses:"},
+	"eks":        {url: "https://{region}.{console}/eks/clusters?region={region}"},
+	"groups":     {url: "https://{region}.{console}/iam/home?region={region}#/groups", global: true},
+	"home":       {url: "https://{region}.{console}/console/home?region={region}"},
+	"iam":        {url: "https://{region}.{console}/iam/home?region={region}#/home", global: true},
+	"kms":        {url: "https://{region}.{console}/kms/home?region={region}#/kms/home"},
+	"org":        {url: "https://{region}.{console}/organizations/v2/home?region={region}", partitions: []string{"aws", "aws-cn", "aws-us-gov"}, global: true},
+	"policies":   {url: "https://{region}.{console}/iam/home?region={region}#/policies", global: true},
+	"r53":        {url: "https://{region}.{console}/route53/v2/hostedzones?region={region}", global: true},
+	"rds":        {url: "https://{region}.{console}/rds/home?region={region}#databases:"},
+	"roles":      {url: "https://{region}.{console}/iam/home?region={region}#/roles", global: true},
+	"s3":         {url: "https://{region}.{console}/s3/buckets?region={region}"},
+	"support":    {url: "https://support.{console}/support/home?region={region}#/case/history", partitions: []string{"aws"}, global: true},
+	"users":      {url: "https://{region}.{console}/iam/home?region={region}#/users", global: true},
+	"vpc":        {url: "https://{region}.{console}/vpcconsole/home?region={region}#vpcs:"},
+	"vpn":        {url: "https://{region}.{console}/vpcconsole/home?region={region}#ClientVPNEndpoints:"},
 }
 
-func resolveLocationAlias(alias, consoleDomain, region string) (string, bool) {
+// resolveLocationAlias resolves the given alias (or URL) into a URL in the AWS
+// Console, using the given location aliases. Pages for global services use the
+// home region of the partition, instead of the given console region. Returns
+// an error if the alias is not known, or if the page is not available in the
+// partition.
+func resolveLocationAlias(aliases map[string]location, alias, partition, consoleDomain, region string) (string, error) {
 	var template string
 
 	if strings.HasPrefix(alias, "https://") {
 		// Use the alias directly as it was already a URL.
 		template = alias
-	} else if result, found := locations[alias]; found {
+	} else if result, found := aliases[alias]; found {
+		if result.partitions != nil && !slices.Contains(result.partitions, partition) {
+			return "", fmt.Errorf("location %q is not available in the %s partition", alias, partition)
+		}
+
 		// Resolve the alias into a URL.
-		template = result
+		template = result.url
+
+		// Use the home region for global services, if the partition is
+		// known.
+		if home, ok := credentials.HomeRegion(partition); ok && result.global {
+			region = home
+		}
 	} else {
 		// Alias could not be resolved
-		return "", false
+		return "", fmt.Errorf("could not resolve location %q", alias)
 	}
 
 	// Replace all the placeholders.
 	return strings.NewReplacer(
 		"{console}", consoleDomain,
+		"{partition}", partition,
 		"{region}", region,
-	).Replace(template), true
+	).Replace(template), nil
 }
 
 // policies is a list of aliases that can be resolved to IAM policy ARNs. Used
@@ -69,10 +110,12 @@ var policies = map[string]string{ //nolint:gochecknoglobals
 	"ro":       "arn:{partition}:iam::aws:policy/ReadOnlyAccess",
 }
 
-func resolvePolicyAlias(alias, partition string) string {
+// resolvePolicyAlias resolves the given alias (or policy ARN) into a policy
+// ARN, using the given policy aliases.
+func resolvePolicyAlias(aliases map[string]string, alias, partition string) string {
 	template := alias
 
-	if result, found := policies[alias]; found {
+	if result, found := aliases[alias]; found {
 		// Resolve the alias into a URL.
 		template = result
 	}
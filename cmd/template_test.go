@@ -0,0 +1,117 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"testing"
+)
+
+func TestResolveLocationAlias(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		title         string
+		alias         string
+		partition     string
+		consoleDomain string
+		region        string
+		want          string
+		wantErr       string
+	}{
+		{
+			title:         "regional service",
+			alias:         "ec2",
+			partition:     "aws",
+			consoleDomain: "console.aws.amazon.com",
+			region:        "eu-west-1",
+			want:          "https://eu-west-1.console.aws.amazon.com/ec2/home?region=eu-west-1#Instances:",
+		},
+		{
+			title:         "regional service in another partition",
+			alias:         "ec2",
+			partition:     "aws-us-gov",
+			consoleDomain: "console.amazonaws-us-gov.com",
+			region:        "us-gov-east-1",
+			want:          "https://us-gov-east-1.console.amazonaws-us-gov.com/ec2/home?region=us-gov-east-1#Instances:",
+		},
+		{
+			title:         "global service uses home region",
+			alias:         "iam",
+			partition:     "aws",
+			consoleDomain: "console.aws.amazon.com",
+			region:        "eu-west-1",
+			want:          "https://us-east-1.console.aws.amazon.com/iam/home?region=us-east-1#/home",
+		},
+		{
+			title:         "global service uses home region of partition",
+			alias:         "iam",
+			partition:     "aws-us-gov",
+			consoleDomain: "console.amazonaws-us-gov.com",
+			region:        "us-gov-east-1",
+			want:          "https://us-gov-west-1.console.amazonaws-us-gov.com/iam/home?region=us-gov-west-1#/home",
+		},
+		{
+			title:         "global service in unknown partition keeps region",
+			alias:         "iam",
+			partition:     "aws-example",
+			consoleDomain: "console.example.com",
+			region:        "ex-east-1",
+			want:          "https://ex-east-1.console.example.com/iam/home?region=ex-east-1#/home",
+		},
+		{
+			title:         "service available in partition",
+			alias:         "org",
+			partition:     "aws-us-gov",
+			consoleDomain: "console.amazonaws-us-gov.com",
+			region:        "us-gov-east-1",
+			want:          "https://us-gov-west-1.console.amazonaws-us-gov.com/organizations/v2/home?region=us-gov-west-1",
+		},
+		{
+			title:     "service not available in partition",
+			alias:     "cloudfront",
+			partition: "aws-us-gov",
+			region:    "us-gov-west-1",
+			wantErr:   `location "cloudfront" is not available in the aws-us-gov partition`,
+		},
+		{
+			title:     "service only available in commercial partition",
+			alias:     "support",
+			partition: "aws-cn",
+			region:    "cn-north-1",
+			wantErr:   `location "support" is not available in the aws-cn partition`,
+		},
+		{
+			title:         "url with placeholders",
+			alias:         "https://{region}.{console}/states/home?region={region}#/{partition}",
+			partition:     "aws",
+			consoleDomain: "console.aws.amazon.com",
+			region:        "us-west-2",
+			want:          "https://us-west-2.console.aws.amazon.com/states/home?region=us-west-2#/aws",
+		},
+		{
+			title:   "unknown alias",
+			alias:   "nope",
+			wantErr: `could not resolve location "nope"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := resolveLocationAlias(locations, test.alias, test.partition, test.consoleDomain, test.region)
+
+			switch {
+			case test.wantErr != "" && (err == nil || err.Error() != test.wantErr):
+				t.Fatalf("expected error %q but got %v", test.wantErr, err)
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case got != test.want:
+				t.Fatalf("expected %q but got %q", test.want, got)
+			}
+		})
+	}
+}
@@ -9,22 +9,40 @@ package console
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 // GenerateLoginURL takes the given sts.Credentials and generates a url.URL
-// that can be used to login to the AWS Console.
+// that can be used to login to the AWS Console. The signin token is requested
+// using the given HTTP method, either POST (which sends the credentials in a
+// form-encoded request body) or GET (which sends them in the query string).
+// The given federation URLs are tried in order, and requests that time out or
+// fail with a server error are retried with a backoff before falling over to
+// the next federation URL. Requests are made using the given HTTP client, or
+// http.DefaultClient if none is given. Failures are returned as one of the
+// error types in this package, depending on the kind of failure.
 // See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_providers_enable-console-custom-url.html.
-func GenerateLoginURL(creds *aws.Credentials, federationURL string, duration time.Duration, location, userAgent string) (*url.URL, error) {
-	// timeout is a hardcoded 15 second window for HTTP requests to complete.
-	const timeout = 15 * time.Second
+func GenerateLoginURL(client *http.Client, creds *aws.Credentials, federationURLs []string, method string, duration time.Duration, location, userAgent string) (*url.URL, error) {
+	// maxAttempts is the number of requests made to each federation URL.
+	const maxAttempts = 2
+
+	if len(federationURLs) == 0 {
+		return nil, errors.New("no federation url given")
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
 
 	sessionCreds := map[string]string{
 		"sessionId":    creds.AccessKeyID,
@@ -50,47 +68,181 @@ func GenerateLoginURL(creds *aws.Credentials, federationURL string, duration tim
 		values["SessionDuration"] = strconv.Itoa(int(duration.Seconds()))
 	}
 
-	// Format a url for the get signin token call.
-	signinURL, err := urlParams(federationURL, values)
-	if err != nil {
-		return nil, err
+	for _, federationURL := range federationURLs {
+		for attempt := range maxAttempts {
+			// Wait before retrying a failed request.
+			if attempt > 0 {
+				time.Sleep(backoff(attempt))
+			}
+
+			var token string
+
+			token, err = requestSigninToken(client, federationURL, method, values, duration, userAgent)
+			if err == nil {
+				// Return a formatted URL that can be used to login to the AWS
+				// Console. The signin token is only valid for the federation
+				// URL that issued it.
+				return urlParams(federationURL, map[string]string{
+					"Action":      "login",
+					"Destination": location,
+					"SigninToken": token,
+				})
+			}
+
+			// Give up immediately, unless the failure was temporary.
+			if !isTemporary(err) {
+				return nil, err
+			}
+		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	return nil, err
+}
+
+// requestSigninToken makes a getSigninToken request to the given federation
+// URL, and returns the signin token from the response.
+func requestSigninToken(client *http.Client, federationURL, method string, values map[string]string, duration time.Duration, userAgent string) (string, error) {
+	// defaultTimeout is a 15 second window for HTTP requests to complete,
+	// used when the HTTP client has no timeout of its own.
+	const defaultTimeout = 15 * time.Second
+
+	ctx := context.Background()
+
+	if client.Timeout == 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
 
 	// Construct a request to the federation URL.
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, signinURL.String(), nil)
+	req, err := newSigninTokenRequest(ctx, method, federationURL, values)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	req.Header.Set("User-Agent", userAgent) //nolint:wsl
 
 	// Perform the actual API request.
-	resp, err := http.DefaultClient.Do(req)
+	// The request url is omitted from the error, as it might contain the
+	// credentials.
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return "", &FederationUnavailableError{URL: federationURL, Err: err}
 	}
 	defer resp.Body.Close() //nolint
 
 	// Verify that we received an HTTP 200 OK status code.
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed: %s", resp.Status)
+		return "", responseError(resp, federationURL, duration)
 	}
 
 	// Extract a signin token from the response body.
-	token, err := extractToken(resp.Body)
-	if err != nil {
-		return nil, err
+	return extractToken(resp.Body)
+}
+
+// isTemporary reports whether the given error is for a request that timed
+// out, could not be sent, or failed with a server error, and might therefore
+// succeed if retried.
+func isTemporary(err error) bool {
+	var unavailableErr *FederationUnavailableError
+	if !errors.As(err, &unavailableErr) {
+		return false
+	}
+
+	switch code := unavailableErr.StatusCode; {
+	case code == 0, code == http.StatusTooManyRequests, code >= http.StatusInternalServerError:
+		return true
+	default:
+		return false
 	}
+}
+
+// backoff returns how long to wait before the given retry attempt, which
+// doubles with each attempt and includes some random jitter.
+func backoff(attempt int) time.Duration {
+	// baseDelay is the delay before the first retry attempt.
+	const baseDelay = 500 * time.Millisecond
+
+	delay := baseDelay << (attempt - 1)
+
+	return delay + rand.N(delay/2) //nolint:gosec
+}
+
+// newSigninTokenRequest constructs a getSigninToken request to the given
+// federation URL, with the given parameter values. For POST requests the
+// values are sent as a form-encoded request body, which keeps the credentials
+// out of the request url, and therefore out of any proxy or debugging logs.
+// For GET requests the values are sent in the query string.
+func newSigninTokenRequest(ctx context.Context, method, federationURL string, values map[string]string) (*http.Request, error) {
+	switch method {
+	case http.MethodPost:
+		form := url.Values{}
+		for key, value := range values {
+			form.Set(key, value)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, federationURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded") //nolint:wsl
+
+		return req, nil
+	case http.MethodGet:
+		// Format a url for the get signin token call.
+		signinURL, err := urlParams(federationURL, values)
+		if err != nil {
+			return nil, err
+		}
+
+		return http.NewRequestWithContext(ctx, http.MethodGet, signinURL.String(), nil)
+	default:
+		return nil, fmt.Errorf("unsupported federation method %q", method)
+	}
+}
+
+// responseError returns an error describing the failed response to a
+// getSigninToken request. The request is rejected with an HTTP 400 Bad Request
+// status code when a session duration is included for credentials obtained
+// through role chaining, or when the credentials are invalid. These cannot be
+// told apart reliably, so any such rejection of a request that included a
+// session duration is returned as a *SessionDurationRejectedError.
+func responseError(resp *http.Response, federationURL string, duration time.Duration) error {
+	// maxBodySize is the number of bytes read from the response body.
+	const maxBodySize = 64 * 1024
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	message := responseMessage(body)
+	skew := clockSkew(resp)
+
+	switch {
+	case strings.Contains(strings.ToLower(message), "expired"):
+		return &ExpiredTokenError{Status: resp.Status, Message: message, ClockSkew: skew}
+	case resp.StatusCode == http.StatusBadRequest && duration != 0:
+		return &SessionDurationRejectedError{Status: resp.Status, Message: message, MentionsDuration: mentionsSessionDuration(message), ClockSkew: skew}
+	case resp.StatusCode == http.StatusBadRequest,
+		resp.StatusCode == http.StatusUnauthorized,
+		resp.StatusCode == http.StatusForbidden:
+		return &InvalidCredentialsError{Status: resp.Status, Message: message, ClockSkew: skew}
+	default:
+		return &FederationUnavailableError{URL: federationURL, Status: resp.Status, StatusCode: resp.StatusCode, Message: message, ClockSkew: skew}
+	}
+}
+
+// mentionsSessionDuration reports whether the given error text refers to the
+// SessionDuration parameter. The federation endpoint does not document its
+// error text, so this is only used as an additional signal.
+func mentionsSessionDuration(message string) bool {
+	message = strings.ToLower(strings.ReplaceAll(message, " ", ""))
 
-	// Return a formatted URL that can be used to login to the AWS Console.
-	return urlParams(federationURL, map[string]string{
-		"Action":      "login",
-		"Destination": location,
-		"SigninToken": token,
-	})
+	return strings.Contains(message, "sessionduration")
 }
 
 // extractToken parses the response JSON from a getSigninToken request and
@@ -0,0 +1,306 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package console
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestResponseError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		title      string
+		statusCode int
+		body       string
+		duration   time.Duration
+		wantCode   int
+
+		// wantMentions is whether the session duration is named as the
+		// cause of a rejection.
+		wantMentions bool
+	}{
+		{
+			title:      "expired token",
+			statusCode: http.StatusBadRequest,
+			body:       "The security token included in the request is expired",
+			wantCode:   ExitCodeExpiredToken,
+		},
+		{
+			title:      "rejected request with session duration",
+			statusCode: http.StatusBadRequest,
+			body:       "Bad Request",
+			duration:   time.Hour,
+			wantCode:   ExitCodeSessionDurationRejected,
+		},
+		{
+			// The federation endpoint does not document its error text, so
+			// this is a hypothetical response.
+			title:        "rejected request naming session duration",
+			statusCode:   http.StatusBadRequest,
+			body:         "<html><body>Invalid SessionDuration</body></html>",
+			duration:     time.Hour,
+			wantCode:     ExitCodeSessionDurationRejected,
+			wantMentions: true,
+		},
+		{
+			title:      "invalid credentials",
+			statusCode: http.StatusBadRequest,
+			body:       "Bad Request",
+			wantCode:   ExitCodeInvalidCredentials,
+		},
+		{
+			title:      "forbidden",
+			statusCode: http.StatusForbidden,
+			wantCode:   ExitCodeInvalidCredentials,
+		},
+		{
+			title:      "too many requests",
+			statusCode: http.StatusTooManyRequests,
+			wantCode:   ExitCodeFederationUnavailable,
+		},
+		{
+			title:      "server error",
+			statusCode: http.StatusServiceUnavailable,
+			body:       "Service Unavailable",
+			wantCode:   ExitCodeFederationUnavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			resp := &http.Response{
+				Status:     http.StatusText(test.statusCode),
+				StatusCode: test.statusCode,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(test.body)),
+			}
+
+			var exitErr interface{ ExitCode() int }
+
+			err := responseError(resp, "https://signin.aws.amazon.com/federation", test.duration)
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("expected an error with an exit code but got %v", err)
+			}
+
+			if code := exitErr.ExitCode(); code != test.wantCode {
+				t.Fatalf("expected exit code %d but got %d (%v)", test.wantCode, code, err)
+			}
+
+			var rejectedErr *SessionDurationRejectedError
+			if errors.As(err, &rejectedErr) && rejectedErr.MentionsDuration != test.wantMentions {
+				t.Fatalf("expected session duration to be named %t but got %t", test.wantMentions, rejectedErr.MentionsDuration)
+			}
+		})
+	}
+}
+
+func TestGenerateLoginURL(t *testing.T) {
+	t.Parallel()
+
+	creds := &aws.Credentials{
+		AccessKeyID:     "ASIAEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+	}
+
+	// newServer returns a federation endpoint that responds to each request
+	// with the next of the given status codes, along with a count of the
+	// requests it received.
+	newServer := func(t *testing.T, statusCodes ...int) (*httptest.Server, *atomic.Int32) {
+		t.Helper()
+
+		var requests atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count := int(requests.Add(1))
+
+			if err := r.ParseForm(); err != nil || r.Form.Get("Session") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			if statusCode := statusCodes[min(count, len(statusCodes))-1]; statusCode != http.StatusOK {
+				w.WriteHeader(statusCode)
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"SigninToken": "token"}`))
+		}))
+		t.Cleanup(server.Close)
+
+		return server, &requests
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		for _, method := range []string{http.MethodPost, http.MethodGet} {
+			server, requests := newServer(t, http.StatusOK)
+
+			loginURL, err := GenerateLoginURL(server.Client(), creds, []string{server.URL}, method, time.Hour, "https://console.aws.amazon.com", "test")
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", method, err)
+			}
+
+			if got := loginURL.Query().Get("SigninToken"); got != "token" {
+				t.Fatalf("%s: expected signin token %q but got %q", method, "token", got)
+			}
+
+			if got := requests.Load(); got != 1 {
+				t.Fatalf("%s: expected 1 request but got %d", method, got)
+			}
+		}
+	})
+
+	t.Run("retry after server error", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := newServer(t, http.StatusServiceUnavailable, http.StatusOK)
+
+		loginURL, err := GenerateLoginURL(server.Client(), creds, []string{server.URL}, http.MethodPost, 0, "https://console.aws.amazon.com", "test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.HasPrefix(loginURL.String(), server.URL) {
+			t.Fatalf("expected login URL for %s but got %s", server.URL, loginURL)
+		}
+
+		if got := requests.Load(); got != 2 {
+			t.Fatalf("expected 2 requests but got %d", got)
+		}
+	})
+
+	t.Run("fail over after repeated server errors", func(t *testing.T) {
+		t.Parallel()
+
+		failing, failingRequests := newServer(t, http.StatusServiceUnavailable)
+		working, workingRequests := newServer(t, http.StatusOK)
+
+		loginURL, err := GenerateLoginURL(nil, creds, []string{failing.URL, working.URL}, http.MethodPost, 0, "https://console.aws.amazon.com", "test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The signin token is only valid for the federation URL that issued
+		// it, so the login URL must use that one.
+		if !strings.HasPrefix(loginURL.String(), working.URL) {
+			t.Fatalf("expected login URL for %s but got %s", working.URL, loginURL)
+		}
+
+		if got := failingRequests.Load(); got != 2 {
+			t.Fatalf("expected 2 requests to failing endpoint but got %d", got)
+		}
+
+		if got := workingRequests.Load(); got != 1 {
+			t.Fatalf("expected 1 request to working endpoint but got %d", got)
+		}
+	})
+
+	t.Run("fail over after unreachable endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		unreachable, _ := newServer(t, http.StatusOK)
+		unreachable.Close()
+
+		working, _ := newServer(t, http.StatusOK)
+
+		loginURL, err := GenerateLoginURL(nil, creds, []string{unreachable.URL, working.URL}, http.MethodPost, 0, "https://console.aws.amazon.com", "test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.HasPrefix(loginURL.String(), working.URL) {
+			t.Fatalf("expected login URL for %s but got %s", working.URL, loginURL)
+		}
+	})
+
+	t.Run("no retry after client error", func(t *testing.T) {
+		t.Parallel()
+
+		rejecting, rejectingRequests := newServer(t, http.StatusForbidden)
+		working, workingRequests := newServer(t, http.StatusOK)
+
+		_, err := GenerateLoginURL(nil, creds, []string{rejecting.URL, working.URL}, http.MethodPost, 0, "https://console.aws.amazon.com", "test")
+
+		var invalidErr *InvalidCredentialsError
+		if !errors.As(err, &invalidErr) {
+			t.Fatalf("expected invalid credentials error but got %v", err)
+		}
+
+		if got := rejectingRequests.Load(); got != 1 {
+			t.Fatalf("expected 1 request to rejecting endpoint but got %d", got)
+		}
+
+		if got := workingRequests.Load(); got != 0 {
+			t.Fatalf("expected no requests to working endpoint but got %d", got)
+		}
+	})
+
+	t.Run("every endpoint unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		first, _ := newServer(t, http.StatusBadGateway)
+		second, _ := newServer(t, http.StatusTooManyRequests)
+
+		_, err := GenerateLoginURL(nil, creds, []string{first.URL, second.URL}, http.MethodPost, 0, "https://console.aws.amazon.com", "test")
+
+		var unavailableErr *FederationUnavailableError
+		if !errors.As(err, &unavailableErr) || unavailableErr.URL != second.URL {
+			t.Fatalf("expected federation unavailable error for %s but got %v", second.URL, err)
+		}
+	})
+}
+
+func TestResponseMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		title string
+		body  string
+		want  string
+	}{
+		{
+			title: "plain text",
+			body:  "Bad Request",
+			want:  "Bad Request",
+		},
+		{
+			title: "html",
+			body:  "<html>\n<head><title>Error</title></head>\n<body><p>Bad   Request</p></body>\n</html>",
+			want:  "Error Bad Request",
+		},
+		{
+			title: "truncated",
+			body:  strings.Repeat("a", 300),
+			want:  strings.Repeat("a", 256) + "…",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			if got := responseMessage([]byte(test.body)); got != test.want {
+				t.Fatalf("expected %q but got %q", test.want, got)
+			}
+		})
+	}
+}
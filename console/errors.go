@@ -0,0 +1,246 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package console
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Exit codes for each kind of failure, as returned by the ExitCode method of
+// the corresponding error type. Any other failure uses an exit code of 1.
+const (
+	ExitCodeInvalidCredentials      = 3
+	ExitCodeExpiredToken            = 4
+	ExitCodeSessionDurationRejected = 5
+	ExitCodeFederationUnavailable   = 6
+	ExitCodeUnknownPartition        = 7
+)
+
+// InvalidCredentialsError is returned when the federation endpoint rejects
+// the credentials used to request a signin token.
+type InvalidCredentialsError struct {
+	// Status is the HTTP status of the response.
+	Status string
+
+	// Message is the error text from the response body.
+	Message string
+
+	// ClockSkew is how far the local clock is ahead of the federation
+	// endpoint, according to the Date header of the response.
+	ClockSkew time.Duration
+}
+
+func (e *InvalidCredentialsError) Error() string {
+	return describe("invalid credentials", e.Status, e.Message)
+}
+
+// ExitCode returns ExitCodeInvalidCredentials.
+func (*InvalidCredentialsError) ExitCode() int {
+	return ExitCodeInvalidCredentials
+}
+
+// ExpiredTokenError is returned when the credentials used to request a signin
+// token have expired.
+type ExpiredTokenError struct {
+	// Status is the HTTP status of the response, if a request was made.
+	Status string
+
+	// Message is the error text from the response body, or a description of
+	// when the credentials expired.
+	Message string
+
+	// ClockSkew is how far the local clock is ahead of the federation
+	// endpoint, according to the Date header of the response.
+	ClockSkew time.Duration
+}
+
+func (e *ExpiredTokenError) Error() string {
+	return describe("expired token", e.Status, e.Message)
+}
+
+// ExitCode returns ExitCodeExpiredToken.
+func (*ExpiredTokenError) ExitCode() int {
+	return ExitCodeExpiredToken
+}
+
+// SessionDurationRejectedError is returned when the federation endpoint
+// rejects a request that included a session duration. That happens when the
+// credentials were obtained through role chaining, in which case the request
+// must be retried without a session duration. The endpoint rejects invalid
+// credentials in the same way, so this might also be the cause.
+type SessionDurationRejectedError struct {
+	// Status is the HTTP status of the response.
+	Status string
+
+	// Message is the error text from the response body.
+	Message string
+
+	// MentionsDuration indicates that the error text refers to the session
+	// duration, which makes it the likely cause over invalid credentials.
+	MentionsDuration bool
+
+	// ClockSkew is how far the local clock is ahead of the federation
+	// endpoint, according to the Date header of the response.
+	ClockSkew time.Duration
+}
+
+func (e *SessionDurationRejectedError) Error() string {
+	return describe("session duration rejected, the credentials might be the product of role chaining", e.Status, e.Message)
+}
+
+// ExitCode returns ExitCodeSessionDurationRejected.
+func (*SessionDurationRejectedError) ExitCode() int {
+	return ExitCodeSessionDurationRejected
+}
+
+// FederationUnavailableError is returned when the federation endpoint could
+// not be reached, or failed to handle the request.
+type FederationUnavailableError struct {
+	// URL is the federation endpoint.
+	URL string
+
+	// Status is the HTTP status of the response, if one was received.
+	Status string
+
+	// StatusCode is the HTTP status code of the response, if one was
+	// received.
+	StatusCode int
+
+	// Message is the error text from the response body, if one was received.
+	Message string
+
+	// Err is the underlying error, if no response was received.
+	Err error
+
+	// ClockSkew is how far the local clock is ahead of the federation
+	// endpoint, according to the Date header of the response.
+	ClockSkew time.Duration
+}
+
+func (e *FederationUnavailableError) Error() string {
+	message := e.Message
+	if e.Err != nil {
+		message = e.Err.Error()
+	}
+
+	return describe("federation endpoint "+e.URL+" unavailable", e.Status, message)
+}
+
+func (e *FederationUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns ExitCodeFederationUnavailable.
+func (*FederationUnavailableError) ExitCode() int {
+	return ExitCodeFederationUnavailable
+}
+
+// UnknownPartitionError is returned when the partition, and therefore the
+// federation endpoint, for a region could not be determined.
+type UnknownPartitionError struct {
+	// Region is the region whose partition is unknown.
+	Region string
+
+	// Err is the underlying error, if the region itself is not known.
+	Err error
+}
+
+func (e *UnknownPartitionError) Error() string {
+	if e.Err != nil {
+		return "could not determine partition: " + e.Err.Error()
+	}
+
+	return "could not determine partition for region " + e.Region
+}
+
+func (e *UnknownPartitionError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns ExitCodeUnknownPartition.
+func (*UnknownPartitionError) ExitCode() int {
+	return ExitCodeUnknownPartition
+}
+
+// describe formats an error summary along with the HTTP status and error text
+// from a response, omitting either if they are empty.
+func describe(summary, status, message string) string {
+	if status != "" {
+		summary = fmt.Sprintf("%s: request failed: %s", summary, status)
+	}
+
+	if message != "" {
+		summary += ": " + message
+	}
+
+	return summary
+}
+
+// ClockSkew returns how far the local clock is ahead of the federation
+// endpoint, as reported with the given error. Returns zero if that is not
+// known.
+func ClockSkew(err error) time.Duration {
+	var (
+		invalidErr     *InvalidCredentialsError
+		expiredErr     *ExpiredTokenError
+		rejectedErr    *SessionDurationRejectedError
+		unavailableErr *FederationUnavailableError
+	)
+
+	switch {
+	case errors.As(err, &invalidErr):
+		return invalidErr.ClockSkew
+	case errors.As(err, &expiredErr):
+		return expiredErr.ClockSkew
+	case errors.As(err, &rejectedErr):
+		return rejectedErr.ClockSkew
+	case errors.As(err, &unavailableErr):
+		return unavailableErr.ClockSkew
+	default:
+		return 0
+	}
+}
+
+// clockSkew returns how far the local clock is ahead of the server that sent
+// the given response, according to its Date header. Returns zero if the
+// response has no Date header.
+func clockSkew(resp *http.Response) time.Duration {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0
+	}
+
+	return time.Since(date)
+}
+
+var (
+	// tagPattern matches HTML tags.
+	tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+	// spacePattern matches runs of whitespace.
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// responseMessage returns the error text from a response body, which might be
+// plain text or an HTML document. The text is truncated if it is too long.
+func responseMessage(body []byte) string {
+	// maxLength is the number of characters kept from the error text.
+	const maxLength = 256
+
+	message := tagPattern.ReplaceAllString(string(body), " ")
+	message = strings.TrimSpace(spacePattern.ReplaceAllString(message, " "))
+
+	if runes := []rune(message); len(runes) > maxLength {
+		message = string(runes[:maxLength]) + "…"
+	}
+
+	return message
+}
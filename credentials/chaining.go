@@ -0,0 +1,52 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// IsChainedProfile reports whether the named profile assumes a role using
+// credentials that are themselves for a role, referred to as "role chaining".
+// That is the case when the profile has a role_arn, and its source profile
+// either has a role_arn of its own or uses IAM Identity Center.
+// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_terms-and-concepts.html#iam-term-role-chaining.
+func IsChainedProfile(profile string) bool {
+	shared, err := loadSharedConfigProfile(context.Background(), profile)
+	if err != nil || shared.RoleARN == "" || shared.Source == nil {
+		return false
+	}
+
+	source := shared.Source
+
+	return source.RoleARN != "" || source.SSOSession != nil || source.SSOStartURL != ""
+}
+
+// IsRoleSession reports whether the given credentials are for an assumed role
+// session, by calling STS GetCallerIdentity. Roles assumed using such
+// credentials are the product of role chaining. Credentials without a session
+// token are never for a role session, so no request is made for them.
+func IsRoleSession(creds *aws.Credentials, options STSOptions) (bool, error) {
+	if creds.SessionToken == "" {
+		return false, nil
+	}
+
+	identity, err := callerIdentity(creds, options)
+	if err != nil {
+		return false, err
+	}
+
+	parsed, err := arn.Parse(aws.ToString(identity.Arn))
+	if err != nil {
+		return false, err
+	}
+
+	return strings.HasPrefix(parsed.Resource, "assumed-role/"), nil
+}
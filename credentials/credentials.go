@@ -8,16 +8,19 @@
 package credentials
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go-v2/service/sts/types"
 	"github.com/aws/smithy-go/middleware"
@@ -27,7 +30,9 @@ import (
 // FromConfig retrieves credentials from the AWS cli config files, typically
 // ~/.aws/credentials and ~/.aws/config. Credentials for the named profile are
 // returned, or the default profile if no name is given. Additionally, the
-// value of $AWS_PROFILE will be used if it is set.
+// value of $AWS_PROFILE will be used if it is set. If the profile uses IAM
+// Identity Center and the cached SSO access token is missing or has expired,
+// then an *SSOLoginError is returned.
 func FromConfig(profile string) (*aws.Credentials, string, error) {
 	ctx := context.Background()
 
@@ -38,94 +43,207 @@ func FromConfig(profile string) (*aws.Credentials, string, error) {
 
 	creds, err := cfg.Credentials.Retrieve(ctx)
 	if err != nil {
+		if session := ssoSessionForProfile(ctx, profile); session != nil && !session.hasValidToken() {
+			return nil, "", &SSOLoginError{Session: session, Err: err}
+		}
+
 		return nil, "", err
 	}
 
 	return &creds, cfg.Region, nil
 }
 
+// LabeledCredentials is a set of credentials, along with a label that
+// identifies it among other sets of credentials.
+type LabeledCredentials struct {
+	Label       string
+	Credentials *aws.Credentials
+}
+
 // FromReader retrieves credentials from given io.Reader, typically os.Stdin.
-// Expects JSON data in one of two possible formats. The first is returned by
-// several STS operations (assume-role/get-session-token/etc) and looks like:
-//
-//	{
-//	    "AssumedRoleUser": {...},
-//	    "Credentials": {
-//	        "AccessKeyId":     "...",
-//	        "SecretAccessKey": "...",
-//	        "SessionToken":    "..."
-//	        "Expiration":      "...",
-//	    }
-//	}
-//
-// The second is returned by various AWS cli credential exec plugins, and looks
-// like:
-//
-//	{
-//	    "AccessKeyId":     "...",
-//	    "SecretAccessKey": "...",
-//	    "SessionToken":    "...",
-//	    "Expiration":      "...",
-//	    "Version":         1
-//	}
+// The data is parsed using the named input format, or if no format (or "auto")
+// is given, then each supported format is tried in turn until one succeeds.
+// For formats that can contain multiple sets of credentials (like INI), the
+// given section name is used to choose one.
+// See Formats for the list of supported input formats.
+func FromReader(reader io.Reader, format, section string) (*aws.Credentials, error) {
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCredentials(body, format, section)
+}
+
+// FromReaderAll retrieves one or more sets of credentials from given
+// io.Reader, typically os.Stdin. In addition to everything supported by
+// FromReader, a JSON array or a stream of newline-delimited JSON values is
+// also accepted, where each element is parsed as a separate set of
+// credentials.
 //
-// See https://docs.aws.amazon.com/cli/latest/reference/sts/assume-role.html#output.
-// See https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-sourcing-external.html.
-func FromReader(reader io.Reader) (*aws.Credentials, error) {
-	// Read the entire body, as it will be potentially parsed multiple times.
+// Each set of credentials is labeled using a "Label" field if one was
+// included, the ARN of the assumed role or federated user, the Cognito
+// identity ID, or else its (one-based) position in the input. At least one set
+// of credentials is always returned if there is no error.
+func FromReaderAll(reader io.Reader, format, section string) ([]LabeledCredentials, error) {
 	body, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
 
-	type creds struct {
-		Credentials processcreds.CredentialProcessResponse `json:"Credentials"`
+	// Treat the entire body as a single element if it does not contain
+	// multiple JSON values.
+	elements := splitJSON(body)
+	if elements == nil {
+		elements = [][]byte{body}
+	}
+
+	results := make([]LabeledCredentials, 0, len(elements))
+
+	for index, element := range elements {
+		creds, err := parseCredentials(element, format, section)
+		if err != nil {
+			if len(elements) > 1 {
+				return nil, fmt.Errorf("element %d: %w", index+1, err)
+			}
+
+			return nil, err
+		}
+
+		results = append(results, LabeledCredentials{
+			Label:       credentialsLabel(element, index),
+			Credentials: creds,
+		})
+	}
+
+	// An empty JSON array contains no credentials at all.
+	if len(results) == 0 {
+		return nil, errors.New("no credentials found")
+	}
+
+	return results, nil
+}
+
+// parseCredentials parses the given body using the named input format, or
+// using each supported format in turn.
+func parseCredentials(body []byte, format, section string) (*aws.Credentials, error) {
+	// Parse the body using only the named format, if one was given.
+	if format != "" && format != "auto" {
+		for _, candidate := range formats {
+			if candidate.name != format {
+				continue
+			}
+
+			creds, err := candidate.parse(body, section)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse credentials as %s: %w", format, err)
+			}
+
+			return creds, nil
+		}
+
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+
+	// Parse the body using each format, until one succeeds.
+	var failures []string
+
+	for _, candidate := range formats {
+		creds, err := candidate.parse(body, section)
+		if err == nil {
+			return creds, nil
+		}
+
+		failures = append(failures, fmt.Sprintf("  %s: %s", candidate.name, err))
+	}
+
+	// Credentials could not be parsed using any format.
+	return nil, errors.New("failed to parse credentials, tried formats:\n" + strings.Join(failures, "\n"))
+}
+
+// splitJSON splits the given body into its individual elements, if the body is
+// either a JSON array or a stream of multiple JSON values. Returns nil
+// otherwise.
+func splitJSON(body []byte) [][]byte {
+	var elements []json.RawMessage
+
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		// Split a JSON array.
+		if err := json.Unmarshal(body, &elements); err != nil {
+			return nil
+		}
+	} else {
+		// Split a stream of JSON values.
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		for {
+			var element json.RawMessage
+			if err := decoder.Decode(&element); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil
+			}
+
+			elements = append(elements, element)
+		}
+
+		// A single JSON value is not a stream.
+		if len(elements) < 2 { //nolint:mnd
+			return nil
+		}
 	}
 
-	var result creds
+	results := make([][]byte, 0, len(elements))
+	for _, element := range elements {
+		results = append(results, element)
+	}
+
+	return results
+}
 
-	if err := json.Unmarshal(body, &result); err == nil && result.Credentials.AccessKeyID != "" && result.Credentials.SecretAccessKey != "" {
-		// Credentials were unmarshalled into the entire struct.
-		return &aws.Credentials{
-			AccessKeyID:     result.Credentials.AccessKeyID,
-			SecretAccessKey: result.Credentials.SecretAccessKey,
-			SessionToken:    result.Credentials.SessionToken,
-		}, nil
-	} else if err := json.Unmarshal(body, &result.Credentials); err == nil && result.Credentials.AccessKeyID != "" && result.Credentials.SecretAccessKey != "" {
-		// Credentials were unmarshalled into part of the struct.
-		return &aws.Credentials{
-			AccessKeyID:     result.Credentials.AccessKeyID,
-			SecretAccessKey: result.Credentials.SecretAccessKey,
-			SessionToken:    result.Credentials.SessionToken,
-		}, nil
+// credentialsLabel returns a label for the given element, which is the element
+// at the given index in the input.
+func credentialsLabel(element []byte, index int) string {
+	var fields struct {
+		Label           string `json:"Label"`
+		AssumedRoleUser struct {
+			Arn string `json:"Arn"`
+		} `json:"AssumedRoleUser"`
+		FederatedUser struct {
+			Arn string `json:"Arn"`
+		} `json:"FederatedUser"`
+		IdentityID string `json:"IdentityId"`
 	}
 
-	// Credentials could not be fully unmarshalled.
-	return nil, errors.New("failed to parse credentials")
+	// Ignore any errors, as the element might not even be JSON.
+	_ = json.Unmarshal(element, &fields)
+
+	switch {
+	case fields.Label != "":
+		return fields.Label
+	case fields.AssumedRoleUser.Arn != "":
+		return fields.AssumedRoleUser.Arn
+	case fields.FederatedUser.Arn != "":
+		return fields.FederatedUser.Arn
+	case fields.IdentityID != "":
+		return fields.IdentityID
+	default:
+		return strconv.Itoa(index + 1)
+	}
 }
 
 // FederateUser will federate the given user credentials by calling STS
 // GetFederationToken. If the given credentials are not for a user (like
 // credentials for a role) then they are returned unmodified.
-func FederateUser(creds *aws.Credentials, region, name, policy string, duration time.Duration, userAgent string) (*aws.Credentials, error) {
+func FederateUser(creds *aws.Credentials, options STSOptions, name, policy string, duration time.Duration) (*aws.Credentials, error) {
 	// Only federate if user credentials were given.
 	if creds.SessionToken != "" {
 		return creds, nil
 	}
 
-	client := sts.NewFromConfig(
-		aws.Config{
-			Credentials: credentials.NewStaticCredentialsProvider(
-				creds.AccessKeyID,
-				creds.SecretAccessKey,
-				creds.SessionToken,
-			),
-			Region: region,
-		},
-		func(options *sts.Options) {
-			options.APIOptions = append(options.APIOptions, setUserAgent(userAgent))
-		},
-	)
+	client, err := newSTSClient(creds, options)
+	if err != nil {
+		return nil, err
+	}
 
 	input := sts.GetFederationTokenInput{
 		Name: aws.String(name),
@@ -155,9 +273,72 @@ func FederateUser(creds *aws.Credentials, region, name, policy string, duration
 		AccessKeyID:     aws.ToString(result.Credentials.AccessKeyId),
 		SecretAccessKey: aws.ToString(result.Credentials.SecretAccessKey),
 		SessionToken:    aws.ToString(result.Credentials.SessionToken),
+		CanExpire:       true,
+		Expires:         aws.ToTime(result.Credentials.Expiration),
 	}, nil
 }
 
+// CheckExpiration returns an error if the given credentials have already
+// expired.
+func CheckExpiration(creds *aws.Credentials) error {
+	if !creds.Expired() {
+		return nil
+	}
+
+	return fmt.Errorf("credentials expired at %s (%s ago)", creds.Expires.Local().Format(time.RFC3339), time.Since(creds.Expires).Round(time.Second))
+}
+
+// STSOptions configures the STS client used for making calls.
+type STSOptions struct {
+	// Region is the region to make calls in.
+	Region string
+
+	// Profile is the name of the profile whose settings, like
+	// use_fips_endpoint and use_dualstack_endpoint, apply to the calls. The
+	// default profile is used if no name is given.
+	Profile string
+
+	// EndpointURL overrides the STS endpoint, like a VPC interface endpoint
+	// or a local emulator. The value of $AWS_ENDPOINT_URL_STS (or the
+	// profile's services configuration) is used if no URL is given.
+	EndpointURL string
+
+	// UserAgent is the user agent to use when making calls.
+	UserAgent string
+}
+
+// newSTSClient returns an STS client that makes calls using the given
+// credentials. The endpoint is resolved in the same way as the AWS cli, and
+// honors FIPS and dual-stack endpoint settings from the environment and the
+// AWS cli config files.
+func newSTSClient(creds *aws.Credentials, options STSOptions) (*sts.Client, error) {
+	loadOptions := []func(*config.LoadOptions) error{
+		config.WithRegion(options.Region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			creds.AccessKeyID,
+			creds.SecretAccessKey,
+			creds.SessionToken,
+		)),
+	}
+
+	if options.Profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(options.Profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), loadOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sts.NewFromConfig(cfg, func(stsOptions *sts.Options) {
+		stsOptions.APIOptions = append(stsOptions.APIOptions, setUserAgent(options.UserAgent))
+
+		if options.EndpointURL != "" {
+			stsOptions.BaseEndpoint = aws.String(options.EndpointURL)
+		}
+	}), nil
+}
+
 func setUserAgent(useragent string) func(stack *middleware.Stack) error {
 	return func(stack *middleware.Stack) error {
 		bm := userAgentMiddleware(useragent)
@@ -0,0 +1,85 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFromReaderAll(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		title      string
+		body       string
+		wantLabels []string
+		wantErr    string
+	}{
+		{
+			title:      "single set",
+			body:       `{"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token"}`,
+			wantLabels: []string{"1"},
+		},
+		{
+			title:      "single set of another format",
+			body:       "AWS_ACCESS_KEY_ID=AKIAEXAMPLE\nAWS_SECRET_ACCESS_KEY=secret\n",
+			wantLabels: []string{"1"},
+		},
+		{
+			title: "json array",
+			body: `[
+				{"Label": "production", "AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token"},
+				{"AssumedRoleUser": {"Arn": "arn:aws:sts::123456789012:assumed-role/Ops/me"}, "Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token"}},
+				{"IdentityId": "us-east-1:example", "Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretKey": "secret", "SessionToken": "token"}}
+			]`,
+			wantLabels: []string{"production", "arn:aws:sts::123456789012:assumed-role/Ops/me", "us-east-1:example"},
+		},
+		{
+			title:      "newline-delimited json",
+			body:       "{\"AccessKeyId\": \"ASIAEXAMPLE\", \"SecretAccessKey\": \"secret\"}\n{\"FederatedUser\": {\"Arn\": \"arn:aws:sts::123456789012:federated-user/me\"}, \"Credentials\": {\"AccessKeyId\": \"ASIAEXAMPLE\", \"SecretAccessKey\": \"secret\"}}\n",
+			wantLabels: []string{"1", "arn:aws:sts::123456789012:federated-user/me"},
+		},
+		{
+			title:   "empty json array",
+			body:    `[]`,
+			wantErr: "no credentials found",
+		},
+		{
+			title:   "invalid element",
+			body:    `[{"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret"}, {"AccessKeyId": "ASIAEXAMPLE"}]`,
+			wantErr: "element 2: failed to parse credentials",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			sets, err := FromReaderAll(strings.NewReader(test.body), "auto", "")
+
+			switch {
+			case test.wantErr != "" && err == nil:
+				t.Fatalf("expected error %q but got none", test.wantErr)
+			case test.wantErr != "" && !strings.HasPrefix(err.Error(), test.wantErr):
+				t.Fatalf("expected error %q but got %q", test.wantErr, err)
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.wantErr != "":
+				return
+			}
+
+			labels := make([]string, 0, len(sets))
+			for _, set := range sets {
+				labels = append(labels, set.Label)
+			}
+
+			if strings.Join(labels, ",") != strings.Join(test.wantLabels, ",") {
+				t.Fatalf("expected labels %q but got %q", test.wantLabels, labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
+	"gopkg.in/yaml.v3"
+)
+
+// errMissingKeys is returned by a parser when the input was well-formed, but
+// did not contain an access key ID and secret access key.
+var errMissingKeys = errors.New("missing access key ID or secret access key")
+
+// format is a named parser for a single credentials input format. Formats that
+// can contain multiple sets of credentials use the given section name to
+// choose one.
+type format struct {
+	name  string
+	parse func(body []byte, section string) (*aws.Credentials, error)
+}
+
+// formats is the list of supported credentials input formats, in the order
+// that they are tried when auto-detecting the format.
+var formats = []format{
+	{name: "sts-json", parse: parseSTSJSON},
+	{name: "process-json", parse: parseProcessJSON},
+	{name: "sso-json", parse: parseSSOJSON},
+	{name: "cognito-json", parse: parseCognitoJSON},
+	{name: "env", parse: parseEnv},
+	{name: "ini", parse: parseINICredentials},
+	{name: "csv", parse: parseCSV},
+	{name: "sts-yaml", parse: parseSTSYAML},
+	{name: "sts-text", parse: parseSTSText},
+}
+
+// Formats returns the names of every supported credentials input format.
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for _, format := range formats {
+		names = append(names, format.name)
+	}
+
+	return names
+}
+
+// parseSTSJSON parses the JSON returned by several STS operations
+// (assume-role/get-session-token/etc) which looks like:
+//
+//	{
+//	    "AssumedRoleUser": {...},
+//	    "Credentials": {
+//	        "AccessKeyId":     "...",
+//	        "SecretAccessKey": "...",
+//	        "SessionToken":    "..."
+//	        "Expiration":      "...",
+//	    }
+//	}
+//
+// See https://docs.aws.amazon.com/cli/latest/reference/sts/assume-role.html#output.
+func parseSTSJSON(body []byte, _ string) (*aws.Credentials, error) {
+	var result struct {
+		Credentials processcreds.CredentialProcessResponse `json:"Credentials"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Credentials.AccessKeyID == "" || result.Credentials.SecretAccessKey == "" {
+		return nil, errMissingKeys
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+		CanExpire:       result.Credentials.Expiration != nil,
+		Expires:         aws.ToTime(result.Credentials.Expiration),
+	}, nil
+}
+
+// parseProcessJSON parses the JSON returned by various AWS cli credential exec
+// plugins, which looks like:
+//
+//	{
+//	    "AccessKeyId":     "...",
+//	    "SecretAccessKey": "...",
+//	    "SessionToken":    "...",
+//	    "Expiration":      "...",
+//	    "Version":         1
+//	}
+//
+// See https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-sourcing-external.html.
+func parseProcessJSON(body []byte, _ string) (*aws.Credentials, error) {
+	var result processcreds.CredentialProcessResponse
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.AccessKeyID == "" || result.SecretAccessKey == "" {
+		return nil, errMissingKeys
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     result.AccessKeyID,
+		SecretAccessKey: result.SecretAccessKey,
+		SessionToken:    result.SessionToken,
+		CanExpire:       result.Expiration != nil,
+		Expires:         aws.ToTime(result.Expiration),
+	}, nil
+}
+
+// parseSSOJSON parses the JSON returned by the SSO get-role-credentials
+// operation, which includes an expiration in epoch milliseconds, and looks
+// like:
+//
+//	{
+//	    "roleCredentials": {
+//	        "accessKeyId":     "...",
+//	        "secretAccessKey": "...",
+//	        "sessionToken":    "...",
+//	        "expiration":      0
+//	    }
+//	}
+//
+// See https://docs.aws.amazon.com/cli/latest/reference/sso/get-role-credentials.html#output.
+func parseSSOJSON(body []byte, _ string) (*aws.Credentials, error) {
+	var result struct {
+		RoleCredentials struct {
+			AccessKeyID     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			Expiration      int64  `json:"expiration"`
+		} `json:"roleCredentials"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.RoleCredentials.AccessKeyID == "" || result.RoleCredentials.SecretAccessKey == "" {
+		return nil, errMissingKeys
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     result.RoleCredentials.AccessKeyID,
+		SecretAccessKey: result.RoleCredentials.SecretAccessKey,
+		SessionToken:    result.RoleCredentials.SessionToken,
+		CanExpire:       result.RoleCredentials.Expiration != 0,
+		Expires:         time.UnixMilli(result.RoleCredentials.Expiration),
+	}, nil
+}
+
+// parseCognitoJSON parses the JSON returned by the Cognito Identity
+// get-credentials-for-identity operation, which names the secret access key
+// differently, and looks like:
+//
+//	{
+//	    "IdentityId": "...",
+//	    "Credentials": {
+//	        "AccessKeyId":  "...",
+//	        "SecretKey":    "...",
+//	        "SessionToken": "...",
+//	        "Expiration":   "..."
+//	    }
+//	}
+//
+// See https://docs.aws.amazon.com/cli/latest/reference/cognito-identity/get-credentials-for-identity.html#output.
+func parseCognitoJSON(body []byte, _ string) (*aws.Credentials, error) {
+	var result struct {
+		IdentityID  string `json:"IdentityId"`
+		Credentials struct {
+			AccessKeyID  string    `json:"AccessKeyId"`
+			SecretKey    string    `json:"SecretKey"`
+			SessionToken string    `json:"SessionToken"`
+			Expiration   timestamp `json:"Expiration"`
+		} `json:"Credentials"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Credentials.AccessKeyID == "" || result.Credentials.SecretKey == "" {
+		return nil, errMissingKeys
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretKey,
+		SessionToken:    result.Credentials.SessionToken,
+		CanExpire:       !time.Time(result.Credentials.Expiration).IsZero(),
+		Expires:         time.Time(result.Credentials.Expiration),
+	}, nil
+}
+
+// parseEnv parses shell-style variable assignments, as output by the AWS cli
+// export-credentials operation (using the env, env-no-export, or windows-cmd
+// formats) or as found in a dotenv file, which look like:
+//
+//	export AWS_ACCESS_KEY_ID=...
+//	export AWS_SECRET_ACCESS_KEY=...
+//	export AWS_SESSION_TOKEN=...
+//	export AWS_CREDENTIAL_EXPIRATION=...
+//
+// See https://docs.aws.amazon.com/cli/latest/reference/configure/export-credentials.html.
+func parseEnv(body []byte, _ string) (*aws.Credentials, error) {
+	var creds aws.Credentials
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip blank lines and comments.
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Remove any leading shell keyword.
+		for _, prefix := range []string{"export ", "set "} {
+			line = strings.TrimPrefix(line, prefix)
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.ContainsAny(key, " \t\"'{}[]") {
+			return nil, fmt.Errorf("line %d is not a variable assignment", lineNumber)
+		}
+
+		// Remove any surrounding quotes.
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		switch key {
+		case "AWS_ACCESS_KEY_ID":
+			creds.AccessKeyID = value
+		case "AWS_SECRET_ACCESS_KEY":
+			creds.SecretAccessKey = value
+		case "AWS_SESSION_TOKEN", "AWS_SECURITY_TOKEN":
+			creds.SessionToken = value
+		case "AWS_CREDENTIAL_EXPIRATION":
+			expires, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid AWS_CREDENTIAL_EXPIRATION: %w", err)
+			}
+
+			creds.CanExpire, creds.Expires = true, expires
+		}
+	}
+
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errMissingKeys
+	}
+
+	return &creds, nil
+}
+
+// parseINICredentials parses a block of INI sections, in the same format as the
+// AWS cli credentials file, which looks like:
+//
+//	[default]
+//	aws_access_key_id     = ...
+//	aws_secret_access_key = ...
+//	aws_session_token     = ...
+//
+// Credentials are read from the named section if one is given. Otherwise, the
+// default section is used, or the only section if there is just one.
+// See https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-files.html.
+func parseINICredentials(body []byte, section string) (*aws.Credentials, error) {
+	sections := parseINI(body)
+	if len(sections) == 0 {
+		return nil, errors.New("no sections found")
+	}
+
+	// List the available section names for use in error messages.
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var (
+		properties map[string]string
+		found      bool
+	)
+
+	switch {
+	case section != "":
+		// Sections from the AWS cli config file are prefixed with "profile".
+		if properties, found = sections[section]; !found {
+			properties, found = sections["profile "+section]
+		}
+	case len(sections) == 1:
+		properties, found = sections[names[0]], true
+	default:
+		properties, found = sections["default"]
+	}
+
+	if !found {
+		return nil, fmt.Errorf("could not choose a section, use one of: %s", strings.Join(names, ", "))
+	}
+
+	if properties["aws_access_key_id"] == "" || properties["aws_secret_access_key"] == "" {
+		return nil, errMissingKeys
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     properties["aws_access_key_id"],
+		SecretAccessKey: properties["aws_secret_access_key"],
+		SessionToken:    properties["aws_session_token"],
+	}, nil
+}
+
+// parseCSV parses the CSV file downloaded from the IAM console after creating
+// an access key, which looks like:
+//
+//	Access key ID,Secret access key
+//	...,...
+//
+// Additional columns (such as those included when creating a user) are
+// ignored, as is a leading UTF-8 byte order mark (as added by some
+// spreadsheet applications).
+// See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_credentials_access-keys.html.
+func parseCSV(body []byte, _ string) (*aws.Credentials, error) {
+	body = bytes.TrimPrefix(body, []byte("\ufeff"))
+
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) < 2 { //nolint:mnd
+		return nil, errors.New("expected a header row and at least one credentials row")
+	}
+
+	// Find the columns that contain the access key ID and secret access
+	// key, using the header row.
+	accessKeyColumn, secretKeyColumn := -1, -1
+
+	for column, name := range records[0] {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "access key id":
+			accessKeyColumn = column
+		case "secret access key":
+			secretKeyColumn = column
+		}
+	}
+
+	if accessKeyColumn < 0 || secretKeyColumn < 0 {
+		return nil, errors.New("missing access key ID or secret access key column")
+	}
+
+	record := records[1]
+	if len(record) <= max(accessKeyColumn, secretKeyColumn) || record[accessKeyColumn] == "" || record[secretKeyColumn] == "" {
+		return nil, errMissingKeys
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     strings.TrimSpace(record[accessKeyColumn]),
+		SecretAccessKey: strings.TrimSpace(record[secretKeyColumn]),
+	}, nil
+}
+
+// parseSTSYAML parses the YAML output of several STS operations
+// (assume-role/get-session-token/etc) when the AWS cli is configured with
+// "output = yaml", which looks like:
+//
+//	AssumedRoleUser:
+//	  ...
+//	Credentials:
+//	  AccessKeyId: ...
+//	  Expiration: '...'
+//	  SecretAccessKey: ...
+//	  SessionToken: ...
+//
+// See https://docs.aws.amazon.com/cli/latest/userguide/cli-usage-output-format.html#yaml-output.
+func parseSTSYAML(body []byte, _ string) (*aws.Credentials, error) {
+	var result struct {
+		Credentials struct {
+			AccessKeyID     string `yaml:"AccessKeyId"`
+			SecretAccessKey string `yaml:"SecretAccessKey"`
+			SessionToken    string `yaml:"SessionToken"`
+			Expiration      string `yaml:"Expiration"`
+		} `yaml:"Credentials"`
+	}
+
+	if err := yaml.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	return stsCredentials(
+		result.Credentials.AccessKeyID,
+		result.Credentials.SecretAccessKey,
+		result.Credentials.SessionToken,
+		result.Credentials.Expiration,
+	)
+}
+
+// parseSTSText parses the tab-separated text output of the STS assume-role,
+// get-session-token, and get-federation-token operations when the AWS cli is
+// configured with "output = text". The columns of the CREDENTIALS row are in
+// alphabetical order of their field names, and looks like:
+//
+//	ASSUMEDROLEUSER  ...  ...
+//	CREDENTIALS      <AccessKeyId>  <Expiration>  <SecretAccessKey>  <SessionToken>
+//
+// See https://docs.aws.amazon.com/cli/latest/userguide/cli-usage-output-format.html#text-output.
+func parseSTSText(body []byte, _ string) (*aws.Credentials, error) {
+	// The number of columns in the CREDENTIALS row, including the row name.
+	const columns = 5
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if fields[0] != "CREDENTIALS" {
+			continue
+		}
+
+		if len(fields) != columns {
+			return nil, fmt.Errorf("expected %d columns in CREDENTIALS row but found %d", columns, len(fields))
+		}
+
+		return stsCredentials(fields[1], fields[3], fields[4], fields[2])
+	}
+
+	return nil, errors.New("missing CREDENTIALS row")
+}
+
+// stsCredentials returns credentials made from the given STS response fields,
+// where the expiration is an RFC 3339 formatted timestamp.
+func stsCredentials(accessKeyID, secretAccessKey, sessionToken, expiration string) (*aws.Credentials, error) {
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, errMissingKeys
+	}
+
+	creds := aws.Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+	}
+
+	if expiration != "" {
+		expires, err := time.Parse(time.RFC3339, expiration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiration: %w", err)
+		}
+
+		creds.CanExpire, creds.Expires = true, expires
+	}
+
+	return &creds, nil
+}
+
+// timestamp is a time.Time that can be unmarshalled from either an RFC 3339
+// formatted string (as output by the AWS cli) or a number of epoch seconds (as
+// returned by the underlying APIs). A null value leaves the time unset.
+type timestamp time.Time
+
+func (t *timestamp) UnmarshalJSON(body []byte) error {
+	// Leave the timestamp unset if it is null, as otherwise that would be
+	// unmarshalled as zero epoch seconds.
+	if string(body) == "null" {
+		return nil
+	}
+
+	var seconds float64
+	if err := json.Unmarshal(body, &seconds); err == nil {
+		*t = timestamp(time.Unix(int64(seconds), 0))
+
+		return nil
+	}
+
+	var value time.Time
+	if err := json.Unmarshal(body, &value); err != nil {
+		return err
+	}
+
+	*t = timestamp(value)
+
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// expiration is the expiration used by each set of test credentials.
+var expiration = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestParseCredentials(t *testing.T) {
+	t.Parallel()
+
+	userCreds := &aws.Credentials{
+		AccessKeyID:     "AKIAEXAMPLE",
+		SecretAccessKey: "secret",
+	}
+
+	sessionCreds := &aws.Credentials{
+		AccessKeyID:     "ASIAEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+	}
+
+	expiringCreds := &aws.Credentials{
+		AccessKeyID:     "ASIAEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+		CanExpire:       true,
+		Expires:         expiration,
+	}
+
+	tests := []struct {
+		title   string
+		format  string
+		section string
+		body    string
+		want    *aws.Credentials
+		wantErr string
+	}{
+		{
+			title:  "sts-json",
+			format: "sts-json",
+			body:   `{"AssumedRoleUser": {"Arn": "arn:aws:sts::123456789012:assumed-role/Ops/me"}, "Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "2030-01-02T03:04:05Z"}}`,
+			want:   expiringCreds,
+		},
+		{
+			title:   "sts-json missing keys",
+			format:  "sts-json",
+			body:    `{"Credentials": {"AccessKeyId": "ASIAEXAMPLE"}}`,
+			wantErr: "failed to parse credentials as sts-json: missing access key ID or secret access key",
+		},
+		{
+			title:  "process-json",
+			format: "process-json",
+			body:   `{"Version": 1, "AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "2030-01-02T03:04:05Z"}`,
+			want:   expiringCreds,
+		},
+		{
+			title:  "process-json without expiration",
+			format: "process-json",
+			body:   `{"Version": 1, "AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token"}`,
+			want:   sessionCreds,
+		},
+		{
+			title:  "process-json without session token",
+			format: "process-json",
+			body:   `{"Version": 1, "AccessKeyId": "AKIAEXAMPLE", "SecretAccessKey": "secret"}`,
+			want:   userCreds,
+		},
+		{
+			title:  "sso-json",
+			format: "sso-json",
+			body:   `{"roleCredentials": {"accessKeyId": "ASIAEXAMPLE", "secretAccessKey": "secret", "sessionToken": "token", "expiration": 1893553445000}}`,
+			want:   expiringCreds,
+		},
+		{
+			title:  "cognito-json with epoch seconds",
+			format: "cognito-json",
+			body:   `{"IdentityId": "us-east-1:example", "Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretKey": "secret", "SessionToken": "token", "Expiration": 1893553445}}`,
+			want:   expiringCreds,
+		},
+		{
+			title:  "cognito-json with timestamp",
+			format: "cognito-json",
+			body:   `{"IdentityId": "us-east-1:example", "Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretKey": "secret", "SessionToken": "token", "Expiration": "2030-01-02T03:04:05Z"}}`,
+			want:   expiringCreds,
+		},
+		{
+			title:  "cognito-json with null expiration",
+			format: "cognito-json",
+			body:   `{"IdentityId": "us-east-1:example", "Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretKey": "secret", "SessionToken": "token", "Expiration": null}}`,
+			want:   sessionCreds,
+		},
+		{
+			title:  "env",
+			format: "env",
+			body:   "export AWS_ACCESS_KEY_ID=ASIAEXAMPLE\nexport AWS_SECRET_ACCESS_KEY=secret\nexport AWS_SESSION_TOKEN=token\nexport AWS_CREDENTIAL_EXPIRATION=2030-01-02T03:04:05Z\n",
+			want:   expiringCreds,
+		},
+		{
+			title:  "env with quotes and comments",
+			format: "env",
+			body:   "# credentials\nset AWS_ACCESS_KEY_ID=\"ASIAEXAMPLE\"\n\nAWS_SECRET_ACCESS_KEY='secret'\nAWS_SECURITY_TOKEN=token\n",
+			want:   sessionCreds,
+		},
+		{
+			title:   "env with invalid line",
+			format:  "env",
+			body:    "AWS_ACCESS_KEY_ID=ASIAEXAMPLE\nnot an assignment\n",
+			wantErr: "failed to parse credentials as env: line 2 is not a variable assignment",
+		},
+		{
+			title:  "ini with single section",
+			format: "ini",
+			body:   "[production]\naws_access_key_id = AKIAEXAMPLE\naws_secret_access_key = secret\n",
+			want:   userCreds,
+		},
+		{
+			title:  "ini with default section",
+			format: "ini",
+			body:   "[default]\naws_access_key_id = AKIAEXAMPLE\naws_secret_access_key = secret\n\n[other]\naws_access_key_id = AKIAOTHER\naws_secret_access_key = other\n",
+			want:   userCreds,
+		},
+		{
+			title:   "ini with named section",
+			format:  "ini",
+			section: "production",
+			body:    "[default]\naws_access_key_id = AKIAOTHER\naws_secret_access_key = other\n\n[profile production]\naws_access_key_id = AKIAEXAMPLE\naws_secret_access_key = secret\n",
+			want:    userCreds,
+		},
+		{
+			title:   "ini with ambiguous sections",
+			format:  "ini",
+			body:    "[staging]\naws_access_key_id = AKIAOTHER\naws_secret_access_key = other\n\n[production]\naws_access_key_id = AKIAEXAMPLE\naws_secret_access_key = secret\n",
+			wantErr: "failed to parse credentials as ini: could not choose a section, use one of: production, staging",
+		},
+		{
+			title:  "csv",
+			format: "csv",
+			body:   "Access key ID,Secret access key\r\nAKIAEXAMPLE,secret\r\n",
+			want:   userCreds,
+		},
+		{
+			title:  "csv with byte order mark",
+			format: "csv",
+			body:   "\ufeffAccess key ID,Secret access key\r\nAKIAEXAMPLE,secret\r\n",
+			want:   userCreds,
+		},
+		{
+			title:  "csv with additional columns",
+			format: "csv",
+			body:   "User name,Password,Access key ID,Secret access key,Console login link\nme,hunter2,AKIAEXAMPLE,secret,https://example.com\n",
+			want:   userCreds,
+		},
+		{
+			title:   "csv without credentials columns",
+			format:  "csv",
+			body:    "User name,Password\nme,hunter2\n",
+			wantErr: "failed to parse credentials as csv: missing access key ID or secret access key column",
+		},
+		{
+			title:  "sts-yaml",
+			format: "sts-yaml",
+			body:   "AssumedRoleUser:\n  Arn: arn:aws:sts::123456789012:assumed-role/Ops/me\nCredentials:\n  AccessKeyId: ASIAEXAMPLE\n  Expiration: '2030-01-02T03:04:05+00:00'\n  SecretAccessKey: secret\n  SessionToken: token\n",
+			want:   expiringCreds,
+		},
+		{
+			title:  "sts-text",
+			format: "sts-text",
+			body:   "ASSUMEDROLEUSER\tarn:aws:sts::123456789012:assumed-role/Ops/me\tAROAEXAMPLE:me\nCREDENTIALS\tASIAEXAMPLE\t2030-01-02T03:04:05+00:00\tsecret\ttoken\n",
+			want:   expiringCreds,
+		},
+		{
+			title:   "sts-text with missing columns",
+			format:  "sts-text",
+			body:    "CREDENTIALS\tASIAEXAMPLE\tsecret\n",
+			wantErr: "failed to parse credentials as sts-text: expected 5 columns in CREDENTIALS row but found 3",
+		},
+		{
+			title: "auto detected sts-json",
+			body:  `{"Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "2030-01-02T03:04:05Z"}}`,
+			want:  expiringCreds,
+		},
+		{
+			title: "auto detected cognito-json",
+			body:  `{"IdentityId": "us-east-1:example", "Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretKey": "secret", "SessionToken": "token"}}`,
+			want:  sessionCreds,
+		},
+		{
+			title: "auto detected env",
+			body:  "AWS_ACCESS_KEY_ID=AKIAEXAMPLE\nAWS_SECRET_ACCESS_KEY=secret\n",
+			want:  userCreds,
+		},
+		{
+			title: "auto detected csv",
+			body:  "\ufeffAccess key ID,Secret access key\nAKIAEXAMPLE,secret\n",
+			want:  userCreds,
+		},
+		{
+			title:   "auto detected nothing",
+			body:    "hello world",
+			wantErr: "failed to parse credentials, tried formats:",
+		},
+		{
+			title:   "unknown format",
+			format:  "xml",
+			body:    "<credentials/>",
+			wantErr: `unknown input format "xml"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			creds, err := parseCredentials([]byte(test.body), test.format, test.section)
+
+			switch {
+			case test.wantErr != "" && err == nil:
+				t.Fatalf("expected error %q but got none", test.wantErr)
+			case test.wantErr != "" && !strings.HasPrefix(err.Error(), test.wantErr):
+				t.Fatalf("expected error %q but got %q", test.wantErr, err)
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.wantErr == "" && !equalCredentials(creds, test.want):
+				t.Fatalf("expected credentials %+v but got %+v", *test.want, *creds)
+			}
+		})
+	}
+}
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		title   string
+		body    string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			title: "epoch seconds",
+			body:  `1893553445`,
+			want:  expiration,
+		},
+		{
+			title: "fractional epoch seconds",
+			body:  `1893553445.5`,
+			want:  expiration,
+		},
+		{
+			title: "timestamp",
+			body:  `"2030-01-02T03:04:05Z"`,
+			want:  expiration,
+		},
+		{
+			title: "null",
+			body:  `null`,
+		},
+		{
+			title:   "invalid",
+			body:    `"tomorrow"`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			t.Parallel()
+
+			var value timestamp
+
+			err := value.UnmarshalJSON([]byte(test.body))
+
+			switch {
+			case test.wantErr && err == nil:
+				t.Fatal("expected error but got none")
+			case !test.wantErr && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case !time.Time(value).Equal(test.want):
+				t.Fatalf("expected %s but got %s", test.want, time.Time(value))
+			}
+		})
+	}
+}
+
+// equalCredentials reports whether the given credentials are the same.
+func equalCredentials(a, b *aws.Credentials) bool {
+	return a.AccessKeyID == b.AccessKeyID &&
+		a.SecretAccessKey == b.SecretAccessKey &&
+		a.SessionToken == b.SessionToken &&
+		a.CanExpire == b.CanExpire &&
+		(!a.CanExpire || a.Expires.Equal(b.Expires))
+}
@@ -0,0 +1,65 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// NewHTTPClient returns an HTTP client with the given timeout, that honors the
+// same CA bundle and proxy settings as the AWS cli. The CA bundle is read from
+// the file named by $AWS_CA_BUNDLE, or the ca_bundle setting of the named
+// profile, and replaces the system certificate pool. Proxies are configured
+// using $HTTPS_PROXY, $HTTP_PROXY, and $NO_PROXY.
+// See https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-envvars.html.
+func NewHTTPClient(profile string, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.Proxy = http.ProxyFromEnvironment
+
+	if filename := caBundleFilename(profile); filename != "" {
+		body, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(body) {
+			return nil, fmt.Errorf("failed to load CA bundle %s: no certificates found", filename)
+		}
+
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    pool,
+		}
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}, nil
+}
+
+// caBundleFilename returns the name of the CA bundle file, either from
+// $AWS_CA_BUNDLE or the ca_bundle setting of the named profile. Returns an
+// empty string if neither is set.
+func caBundleFilename(profile string) string {
+	if filename := os.Getenv("AWS_CA_BUNDLE"); filename != "" {
+		return filename
+	}
+
+	shared, err := loadSharedConfigProfile(context.Background(), profile)
+	if err != nil {
+		return ""
+	}
+
+	return shared.CustomCABundle
+}
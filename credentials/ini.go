@@ -0,0 +1,144 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// iniSections is a parsed INI document, keyed by section name and then by
+// property name.
+type iniSections map[string]map[string]string
+
+// parseINI parses the given INI document, in the format used by the AWS cli
+// config files. Comments, blank lines, and indented sub-properties are
+// ignored.
+func parseINI(body []byte) iniSections {
+	var (
+		sections = iniSections{}
+		section  map[string]string
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Skip indented sub-properties, as they are not needed.
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "", strings.HasPrefix(line, "#"), strings.HasPrefix(line, ";"):
+			// Skip blank lines and comments.
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			// Start a new section.
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if _, found := sections[name]; !found {
+				sections[name] = map[string]string{}
+			}
+
+			section = sections[name]
+		case section != nil:
+			// Add a property to the current section.
+			if key, value, found := strings.Cut(line, "="); found {
+				section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	return sections
+}
+
+// loadSharedConfig parses the AWS cli config file, typically ~/.aws/config.
+// The value of $AWS_CONFIG_FILE will be used if it is set.
+func loadSharedConfig() (iniSections, error) {
+	filename := os.Getenv("AWS_CONFIG_FILE")
+	if filename == "" {
+		filename = config.DefaultSharedConfigFilename()
+	}
+
+	body, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseINI(body), nil
+}
+
+// ProfileSettings returns the settings of the named profile in the AWS cli
+// config file, or the default profile if no name is given. This allows for
+// settings that the AWS cli does not know about. The default profile can be
+// named either "default" or "profile default", like with the AWS cli. Returns
+// an empty map if the profile does not exist.
+func ProfileSettings(profile string) map[string]string {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+
+	if profile == "" {
+		profile = config.DefaultSharedConfigProfile
+	}
+
+	sections, err := loadSharedConfig()
+	if err != nil {
+		return map[string]string{}
+	}
+
+	settings := map[string]string{}
+
+	if profile == config.DefaultSharedConfigProfile {
+		maps.Copy(settings, sections[config.DefaultSharedConfigProfile])
+	}
+
+	maps.Copy(settings, sections["profile "+profile])
+
+	return settings
+}
+
+// loadSharedConfigProfile loads the named profile from the AWS cli config
+// files, along with any source profiles. The profile name, and the location of
+// the config files, are resolved in the same way as the AWS cli.
+func loadSharedConfigProfile(ctx context.Context, profile string) (config.SharedConfig, error) {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+
+	if profile == "" {
+		profile = config.DefaultSharedConfigProfile
+	}
+
+	return config.LoadSharedConfigProfile(ctx, profile, func(options *config.LoadSharedConfigOptions) {
+		if filename := os.Getenv("AWS_CONFIG_FILE"); filename != "" {
+			options.ConfigFiles = []string{filename}
+		}
+
+		if filename := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); filename != "" {
+			options.CredentialsFiles = []string{filename}
+		}
+	})
+}
+
+// ProfileExists reports whether a profile with the given name exists in the
+// AWS cli config files.
+func ProfileExists(profile string) bool {
+	_, err := loadSharedConfigProfile(context.Background(), profile)
+
+	var notExistErr config.SharedConfigProfileNotExistError
+
+	return err == nil || !errors.As(err, &notExistErr)
+}
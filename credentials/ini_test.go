@@ -0,0 +1,52 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileSettings(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config")
+
+	body := `[default]
+region = us-east-1
+console_domain = default.example.com
+
+[profile default]
+federation_url = https://default.example.com/federation
+
+[profile production]
+console_domain = production.example.com
+`
+	if err := os.WriteFile(filename, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AWS_CONFIG_FILE", filename)
+	t.Setenv("AWS_PROFILE", "")
+
+	tests := []struct {
+		profile string
+		key     string
+		want    string
+	}{
+		{profile: "", key: "console_domain", want: "default.example.com"},
+		{profile: "", key: "federation_url", want: "https://default.example.com/federation"},
+		{profile: "default", key: "federation_url", want: "https://default.example.com/federation"},
+		{profile: "production", key: "console_domain", want: "production.example.com"},
+		{profile: "production", key: "federation_url", want: ""},
+		{profile: "missing", key: "console_domain", want: ""},
+	}
+
+	for _, test := range tests {
+		if got := ProfileSettings(test.profile)[test.key]; got != test.want {
+			t.Errorf("profile %q: expected %s of %q but got %q", test.profile, test.key, test.want, got)
+		}
+	}
+}
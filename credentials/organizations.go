@@ -0,0 +1,110 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+)
+
+// accountIDPattern matches a 12-digit AWS account ID.
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// Account is a member account of an AWS organization.
+type Account struct {
+	ID    string
+	Name  string
+	Email string
+	State string
+}
+
+// IsAccountID reports whether the given value is a 12-digit AWS account ID.
+func IsAccountID(value string) bool {
+	return accountIDPattern.MatchString(value)
+}
+
+// RoleARN returns the ARN for the named IAM role in the given account.
+func RoleARN(partition, accountID, roleName string) string {
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, accountID, roleName)
+}
+
+// ListAccounts lists every member account in the organization by calling
+// Organizations ListAccounts. The given credentials must belong to either the
+// management account or a delegated administrator account.
+func ListAccounts(creds *aws.Credentials, region, userAgent string) ([]Account, error) {
+	client := organizations.NewFromConfig(
+		aws.Config{
+			Credentials: credentials.NewStaticCredentialsProvider(
+				creds.AccessKeyID,
+				creds.SecretAccessKey,
+				creds.SessionToken,
+			),
+			Region: region,
+		},
+		func(options *organizations.Options) {
+			options.APIOptions = append(options.APIOptions, setUserAgent(userAgent))
+		},
+	)
+
+	var accounts []Account
+
+	// Page through the entire list of accounts.
+	paginator := organizations.NewListAccountsPaginator(client, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, account := range page.Accounts {
+			accounts = append(accounts, Account{
+				ID:    aws.ToString(account.Id),
+				Name:  aws.ToString(account.Name),
+				Email: aws.ToString(account.Email),
+				State: string(account.State),
+			})
+		}
+	}
+
+	return accounts, nil
+}
+
+// ResolveAccount resolves the given account ID or account name into an
+// account ID. Account IDs are returned unmodified, while account names are
+// looked up (case-insensitively) in the list of organization member accounts.
+func ResolveAccount(creds *aws.Credentials, region, account, userAgent string) (string, error) {
+	if IsAccountID(account) {
+		return account, nil
+	}
+
+	accounts, err := ListAccounts(creds, region, userAgent)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []Account
+
+	for _, candidate := range accounts {
+		if strings.EqualFold(candidate.Name, account) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("could not find account named %q", account)
+	case 1:
+		return matches[0].ID, nil
+	default:
+		return "", fmt.Errorf("found %d accounts named %q", len(matches), account)
+	}
+}
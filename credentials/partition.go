@@ -6,38 +6,213 @@
 package credentials
 
 import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 var partitionURLs = map[string]struct {
 	consoleDomain string
 	federationURL string
+
+	// homeRegion is the region that global services are located in, which
+	// is also the console region used when none was configured.
+	homeRegion string
+
+	// regionalFederationURL is the federation URL for a single region, with
+	// a {region} placeholder. Empty if the partition has no regional
+	// federation URLs.
+	regionalFederationURL string
+
+	// fallbackRegions are the regions whose federation URLs are tried after
+	// the global federation URL.
+	fallbackRegions []string
+
+	// regionPattern matches the names of regions in the partition, including
+	// those that are not known yet. Taken from the partition metadata of the
+	// AWS SDK.
+	regionPattern *regexp.Regexp
 }{
 	"aws": {
-		consoleDomain: "console.aws.amazon.com",
-		federationURL: "https://signin.aws.amazon.com/federation",
+		consoleDomain:         "console.aws.amazon.com",
+		federationURL:         "https://signin.aws.amazon.com/federation",
+		homeRegion:            "us-east-1",
+		regionalFederationURL: "https://{region}.signin.aws.amazon.com/federation",
+		fallbackRegions:       []string{"us-east-1", "us-west-2", "eu-west-1"},
+		regionPattern:         regexp.MustCompile(`^(us|eu|ap|sa|ca|me|af|il|mx)\-\w+\-\d+$`),
 	},
 	"aws-cn": {
 		// This partition has not been tested.
 		consoleDomain: "console.amazonaws.cn",
 		federationURL: "https://signin.amazonaws.cn/federation",
+		homeRegion:    "cn-north-1",
+		regionPattern: regexp.MustCompile(`^cn\-\w+\-\d+$`),
+	},
+	"aws-eusc": {
+		// This partition has not been tested.
+		consoleDomain: "console.amazonaws.eu",
+		federationURL: "https://signin.amazonaws.eu/federation",
+		homeRegion:    "eusc-de-east-1",
+		regionPattern: regexp.MustCompile(`^eusc\-(de)\-\w+\-\d+$`),
+	},
+	"aws-iso": {
+		// This partition has not been tested.
+		consoleDomain: "console.c2s.ic.gov",
+		federationURL: "https://signin.c2s.ic.gov/federation",
+		homeRegion:    "us-iso-east-1",
+		regionPattern: regexp.MustCompile(`^us\-iso\-\w+\-\d+$`),
+	},
+	"aws-iso-b": {
+		// This partition has not been tested.
+		consoleDomain: "console.sc2s.sgov.gov",
+		federationURL: "https://signin.sc2s.sgov.gov/federation",
+		homeRegion:    "us-isob-east-1",
+		regionPattern: regexp.MustCompile(`^us\-isob\-\w+\-\d+$`),
+	},
+	"aws-iso-e": {
+		// This partition has not been tested.
+		consoleDomain: "console.cloud.adc-e.uk",
+		federationURL: "https://signin.cloud.adc-e.uk/federation",
+		homeRegion:    "eu-isoe-west-1",
+		regionPattern: regexp.MustCompile(`^eu\-isoe\-\w+\-\d+$`),
+	},
+	"aws-iso-f": {
+		// This partition has not been tested.
+		consoleDomain: "console.csp.hci.ic.gov",
+		federationURL: "https://signin.csp.hci.ic.gov/federation",
+		homeRegion:    "us-isof-south-1",
+		regionPattern: regexp.MustCompile(`^us\-isof\-\w+\-\d+$`),
 	},
 	"aws-us-gov": {
 		consoleDomain: "console.amazonaws-us-gov.com",
 		federationURL: "https://signin.amazonaws-us-gov.com/federation",
+		homeRegion:    "us-gov-west-1",
+		regionPattern: regexp.MustCompile(`^us\-gov\-\w+\-\d+$`),
 	},
 }
 
-// ResolveRegionPartition uses the given AWS region to determine the corresponding AWS partition, Console URL, and federation URL.
-func ResolveRegionPartition(region string) (string, string, string, bool) {
-	partition := "aws"
-	if endpoint, err := sts.NewDefaultEndpointResolver().ResolveEndpoint(region, sts.EndpointResolverOptions{}); err == nil {
-		partition = endpoint.PartitionID
+// ResolveRegionPartition uses the given AWS region to determine the corresponding AWS partition, Console URL, and federation URLs.
+// The federation URLs are ordered by preference: the federation URL for the given region, the global federation URL, and then the federation URLs for other regions.
+// If the URLs for the partition are not known, then only the partition is returned.
+func ResolveRegionPartition(region string) (string, string, []string, bool) {
+	// Use the partition of a known region, or otherwise the partition whose
+	// region naming pattern matches.
+	partition, ok := regionPartition(region)
+	if !ok {
+		partition = "aws"
+	}
+
+	urls, ok := partitionURLs[partition]
+	if !ok {
+		return partition, "", nil, false
+	}
+
+	if urls.regionalFederationURL == "" {
+		return partition, urls.consoleDomain, []string{urls.federationURL}, true
+	}
+
+	regionalURL := func(region string) string {
+		return strings.ReplaceAll(urls.regionalFederationURL, "{region}", region)
 	}
 
-	if urls, ok := partitionURLs[partition]; ok {
-		return partition, urls.consoleDomain, urls.federationURL, true
+	federationURLs := []string{regionalURL(region), urls.federationURL}
+
+	for _, fallback := range urls.fallbackRegions {
+		if federationURL := regionalURL(fallback); !slices.Contains(federationURLs, federationURL) {
+			federationURLs = append(federationURLs, federationURL)
+		}
+	}
+
+	return partition, urls.consoleDomain, federationURLs, true
+}
+
+// HomeRegion returns the region that global services, like IAM, are located
+// in for the given partition.
+func HomeRegion(partition string) (string, bool) {
+	urls, ok := partitionURLs[partition]
+
+	return urls.homeRegion, ok
+}
+
+// DefaultRegion returns the console region used for the given partition when
+// none was configured, which is its home region. Falls back to us-east-1 if
+// the partition is not known.
+func DefaultRegion(partition string) string {
+	if region, ok := HomeRegion(partition); ok {
+		return region
+	}
+
+	return "us-east-1"
+}
+
+// ARNPartition returns the partition of the given ARN, or an empty string if
+// it is not a valid ARN.
+func ARNPartition(value string) string {
+	parsed, err := arn.Parse(value)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Partition
+}
+
+// ProfilePartition returns the partition of the role_arn setting of the named
+// profile, or an empty string if the profile does not assume a role.
+func ProfilePartition(profile string) string {
+	shared, err := loadSharedConfigProfile(context.Background(), profile)
+	if err != nil {
+		return ""
+	}
+
+	return ARNPartition(shared.RoleARN)
+}
+
+// CallerPartition returns the partition of the given credentials, according to
+// the ARN returned by STS GetCallerIdentity. As credentials are only valid
+// within their own partition, the call is tried in the given region (if any),
+// and then in the default region of each publicly reachable partition, until
+// it succeeds. If an endpoint URL is given, then only that endpoint is tried.
+func CallerPartition(creds *aws.Credentials, options STSOptions) (string, error) {
+	var regions []string
+	if options.Region != "" {
+		regions = append(regions, options.Region)
+	}
+
+	for _, partition := range []string{"aws", "aws-us-gov", "aws-cn"} {
+		if region := DefaultRegion(partition); !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+
+	if options.EndpointURL != "" {
+		regions = regions[:1]
+	}
+
+	var err error
+
+	for _, region := range regions {
+		options.Region = region
+
+		var identity *sts.GetCallerIdentityOutput
+		if identity, err = callerIdentity(creds, options); err == nil {
+			return ARNPartition(aws.ToString(identity.Arn)), nil
+		}
+	}
+
+	return "", err
+}
+
+// callerIdentity calls STS GetCallerIdentity using the given credentials.
+func callerIdentity(creds *aws.Credentials, options STSOptions) (*sts.GetCallerIdentityOutput, error) {
+	client, err := newSTSClient(creds, options)
+	if err != nil {
+		return nil, err
 	}
 
-	return "", "", "", false
+	return client.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
 }
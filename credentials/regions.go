@@ -0,0 +1,165 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Region is an AWS region, along with the partition it belongs to.
+type Region struct {
+	ID          string
+	Partition   string
+	Description string
+}
+
+// regions is the list of regions in each partition, ordered by partition and
+// then by region. Taken from the partition metadata of the AWS SDK.
+// See https://github.com/aws/aws-sdk-go-v2/blob/main/internal/endpoints/awsrulesfn/partitions.json.
+var regions = []Region{
+	{ID: "af-south-1", Partition: "aws", Description: "Africa (Cape Town)"},
+	{ID: "ap-east-1", Partition: "aws", Description: "Asia Pacific (Hong Kong)"},
+	{ID: "ap-east-2", Partition: "aws", Description: "Asia Pacific (Taipei)"},
+	{ID: "ap-northeast-1", Partition: "aws", Description: "Asia Pacific (Tokyo)"},
+	{ID: "ap-northeast-2", Partition: "aws", Description: "Asia Pacific (Seoul)"},
+	{ID: "ap-northeast-3", Partition: "aws", Description: "Asia Pacific (Osaka)"},
+	{ID: "ap-south-1", Partition: "aws", Description: "Asia Pacific (Mumbai)"},
+	{ID: "ap-south-2", Partition: "aws", Description: "Asia Pacific (Hyderabad)"},
+	{ID: "ap-southeast-1", Partition: "aws", Description: "Asia Pacific (Singapore)"},
+	{ID: "ap-southeast-2", Partition: "aws", Description: "Asia Pacific (Sydney)"},
+	{ID: "ap-southeast-3", Partition: "aws", Description: "Asia Pacific (Jakarta)"},
+	{ID: "ap-southeast-4", Partition: "aws", Description: "Asia Pacific (Melbourne)"},
+	{ID: "ap-southeast-5", Partition: "aws", Description: "Asia Pacific (Malaysia)"},
+	{ID: "ap-southeast-6", Partition: "aws", Description: "Asia Pacific (New Zealand)"},
+	{ID: "ap-southeast-7", Partition: "aws", Description: "Asia Pacific (Thailand)"},
+	{ID: "ca-central-1", Partition: "aws", Description: "Canada (Central)"},
+	{ID: "ca-west-1", Partition: "aws", Description: "Canada West (Calgary)"},
+	{ID: "eu-central-1", Partition: "aws", Description: "Europe (Frankfurt)"},
+	{ID: "eu-central-2", Partition: "aws", Description: "Europe (Zurich)"},
+	{ID: "eu-north-1", Partition: "aws", Description: "Europe (Stockholm)"},
+	{ID: "eu-south-1", Partition: "aws", Description: "Europe (Milan)"},
+	{ID: "eu-south-2", Partition: "aws", Description: "Europe (Spain)"},
+	{ID: "eu-west-1", Partition: "aws", Description: "Europe (Ireland)"},
+	{ID: "eu-west-2", Partition: "aws", Description: "Europe (London)"},
+	{ID: "eu-west-3", Partition: "aws", Description: "Europe (Paris)"},
+	{ID: "il-central-1", Partition: "aws", Description: "Israel (Tel Aviv)"},
+	{ID: "me-central-1", Partition: "aws", Description: "Middle East (UAE)"},
+	{ID: "me-south-1", Partition: "aws", Description: "Middle East (Bahrain)"},
+	{ID: "mx-central-1", Partition: "aws", Description: "Mexico (Central)"},
+	{ID: "sa-east-1", Partition: "aws", Description: "South America (Sao Paulo)"},
+	{ID: "us-east-1", Partition: "aws", Description: "US East (N. Virginia)"},
+	{ID: "us-east-2", Partition: "aws", Description: "US East (Ohio)"},
+	{ID: "us-west-1", Partition: "aws", Description: "US West (N. California)"},
+	{ID: "us-west-2", Partition: "aws", Description: "US West (Oregon)"},
+	{ID: "cn-north-1", Partition: "aws-cn", Description: "China (Beijing)"},
+	{ID: "cn-northwest-1", Partition: "aws-cn", Description: "China (Ningxia)"},
+	{ID: "eusc-de-east-1", Partition: "aws-eusc", Description: "EU (Germany)"},
+	{ID: "us-iso-east-1", Partition: "aws-iso", Description: "US ISO East"},
+	{ID: "us-iso-west-1", Partition: "aws-iso", Description: "US ISO WEST"},
+	{ID: "us-isob-east-1", Partition: "aws-iso-b", Description: "US ISOB East (Ohio)"},
+	{ID: "us-isob-west-1", Partition: "aws-iso-b", Description: "US ISOB West"},
+	{ID: "eu-isoe-west-1", Partition: "aws-iso-e", Description: "EU ISOE West"},
+	{ID: "us-isof-east-1", Partition: "aws-iso-f", Description: "US ISOF EAST"},
+	{ID: "us-isof-south-1", Partition: "aws-iso-f", Description: "US ISOF SOUTH"},
+	{ID: "us-gov-east-1", Partition: "aws-us-gov", Description: "AWS GovCloud (US-East)"},
+	{ID: "us-gov-west-1", Partition: "aws-us-gov", Description: "AWS GovCloud (US-West)"},
+}
+
+// Regions returns the list of known regions, ordered by partition and then by
+// region.
+func Regions() []Region {
+	return slices.Clone(regions)
+}
+
+// lookupRegion returns the known region with the given ID.
+func lookupRegion(id string) (Region, bool) {
+	index := slices.IndexFunc(regions, func(region Region) bool {
+		return region.ID == id
+	})
+	if index < 0 {
+		return Region{}, false
+	}
+
+	return regions[index], true
+}
+
+// regionPartition returns the partition of the given region, which is either
+// a known region, or is named like the regions in that partition. Regions are
+// added more often than they are listed here, so the naming pattern is used
+// for regions that are not known yet.
+func regionPartition(id string) (string, bool) {
+	if region, ok := lookupRegion(id); ok {
+		return region.Partition, true
+	}
+
+	for partition, urls := range partitionURLs {
+		if urls.regionPattern.MatchString(id) {
+			return partition, true
+		}
+	}
+
+	return "", false
+}
+
+// ValidateRegion returns an error if the given region is neither known nor
+// named like the regions in any partition. The error suggests the known region
+// with the most similar name, if there is one.
+func ValidateRegion(id string) error {
+	if _, ok := regionPartition(id); ok {
+		return nil
+	}
+
+	// maxDistance is the number of edits allowed between the given region
+	// and a suggested region.
+	const maxDistance = 3
+
+	var (
+		suggestion string
+		best       = maxDistance + 1
+	)
+
+	for _, region := range regions {
+		if distance := levenshtein(id, region.ID); distance < best {
+			suggestion, best = region.ID, distance
+		}
+	}
+
+	if suggestion == "" {
+		return fmt.Errorf("unknown region %q", id)
+	}
+
+	return fmt.Errorf("unknown region %q, did you mean %q?", id, suggestion)
+}
+
+// levenshtein returns the number of single character insertions, deletions,
+// or substitutions needed to change one string into the other.
+// See https://en.wikipedia.org/wiki/Levenshtein_distance.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := range len(a) {
+		current[0] = i + 1
+
+		for j := range len(b) {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
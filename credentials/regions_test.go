@@ -0,0 +1,104 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"testing"
+)
+
+func TestValidateRegion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		region  string
+		wantErr string
+	}{
+		{region: "us-east-1"},
+		{region: "us-gov-west-1"},
+		{region: "cn-north-1"},
+		{region: "us-east-7"},
+		{region: "us-gov-south-1"},
+		{region: "eu-west", wantErr: `unknown region "eu-west", did you mean "eu-west-1"?`},
+		{region: "useast1", wantErr: `unknown region "useast1", did you mean "us-east-1"?`},
+		{region: "nowhere", wantErr: `unknown region "nowhere"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.region, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateRegion(test.region)
+
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.wantErr != "" && (err == nil || err.Error() != test.wantErr):
+				t.Fatalf("expected error %q but got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRegionPartition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		region        string
+		wantPartition string
+		wantOK        bool
+	}{
+		{region: "us-east-1", wantPartition: "aws", wantOK: true},
+		{region: "us-east-7", wantPartition: "aws", wantOK: true},
+		{region: "cn-south-1", wantPartition: "aws-cn", wantOK: true},
+		{region: "us-gov-south-1", wantPartition: "aws-us-gov", wantOK: true},
+		{region: "us-iso-south-1", wantPartition: "aws-iso", wantOK: true},
+		{region: "us-isob-south-1", wantPartition: "aws-iso-b", wantOK: true},
+		{region: "eu-isoe-south-1", wantPartition: "aws-iso-e", wantOK: true},
+		{region: "us-isof-east-1", wantPartition: "aws-iso-f", wantOK: true},
+		{region: "eusc-de-west-1", wantPartition: "aws-eusc", wantOK: true},
+		{region: "eu-west"},
+		{region: "xx-east-1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.region, func(t *testing.T) {
+			t.Parallel()
+
+			partition, ok := regionPartition(test.region)
+			if partition != test.wantPartition || ok != test.wantOK {
+				t.Fatalf("expected partition %q (%t) but got %q (%t)", test.wantPartition, test.wantOK, partition, ok)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "", b: "abc", want: 3},
+		{a: "abc", b: "", want: 3},
+		{a: "us-east-1", b: "us-east-1", want: 0},
+		{a: "us-east-1", b: "us-east-2", want: 1},
+		{a: "us-esat-1", b: "us-east-1", want: 2},
+		{a: "eu-west", b: "eu-west-1", want: 2},
+		{a: "kitten", b: "sitting", want: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.a+"/"+test.b, func(t *testing.T) {
+			t.Parallel()
+
+			if got := levenshtein(test.a, test.b); got != test.want {
+				t.Fatalf("expected distance %d but got %d", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// AssumeRole uses the given source credentials to assume the given IAM role
+// by calling STS AssumeRole. The external ID and MFA device serial number are
+// only included in the request if they are given.
+func AssumeRole(creds *aws.Credentials, options STSOptions, roleARN, sessionName, externalID, mfaSerial, mfaCode string, duration time.Duration) (*aws.Credentials, error) {
+	client, err := newSTSClient(creds, options)
+	if err != nil {
+		return nil, err
+	}
+
+	input := sts.AssumeRoleInput{
+		RoleArn:         aws.String(roleARN),
+		RoleSessionName: aws.String(sessionName),
+	}
+
+	if externalID != "" {
+		input.ExternalId = aws.String(externalID)
+	}
+
+	if mfaSerial != "" {
+		input.SerialNumber = aws.String(mfaSerial)
+		input.TokenCode = aws.String(mfaCode)
+	}
+
+	// The minimum value for the DurationSeconds parameter is 15 minutes.
+	// See https://docs.aws.amazon.com/STS/latest/APIReference/API_AssumeRole.html#API_AssumeRole_RequestParameters.
+	const minDuration = 15 * time.Minute
+	if duration != 0 && duration < minDuration {
+		duration = minDuration
+	}
+
+	if duration != 0 {
+		input.DurationSeconds = aws.Int32(int32(duration.Seconds()))
+	}
+
+	// Assume the role.
+	result, err := client.AssumeRole(context.Background(), &input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     aws.ToString(result.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(result.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(result.Credentials.SessionToken),
+		CanExpire:       true,
+		Expires:         aws.ToTime(result.Credentials.Expiration),
+	}, nil
+}
+
+// IsDurationExceeded reports whether the given error was returned by
+// AssumeRole because the requested duration exceeds the maximum session
+// duration of the role, or the limit for role chaining.
+func IsDurationExceeded(err error) bool {
+	var apiErr smithy.APIError
+
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "DurationSeconds")
+}
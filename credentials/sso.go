@@ -0,0 +1,261 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+)
+
+// SSOSession is an IAM Identity Center session, as configured by an
+// sso-session section in the AWS cli config file. Legacy profiles that
+// configure a start URL directly are represented by a session without a name.
+type SSOSession struct {
+	Name     string
+	Region   string
+	StartURL string
+}
+
+// SSORole is a role that can be used in an account by way of IAM Identity
+// Center.
+type SSORole struct {
+	AccountID   string
+	AccountName string
+	RoleName    string
+}
+
+// loadSSOSession retrieves the named sso-session section from the AWS cli
+// config file.
+// See https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-sso.html#cli-configure-sso-manual.
+func loadSSOSession(name string) (*SSOSession, error) {
+	sections, err := loadSharedConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	section, found := sections["sso-session "+name]
+	if !found {
+		return nil, fmt.Errorf("could not find sso-session %q", name)
+	}
+
+	session := SSOSession{
+		Name:     name,
+		Region:   section["sso_region"],
+		StartURL: section["sso_start_url"],
+	}
+
+	if session.Region == "" || session.StartURL == "" {
+		return nil, fmt.Errorf("sso-session %q must set sso_region and sso_start_url", name)
+	}
+
+	return &session, nil
+}
+
+// ListSSORoles lists every account and role pair that is available to the
+// named sso-session, by calling SSO ListAccounts and ListAccountRoles. The
+// cached access token for the session is used, and is refreshed if needed.
+func ListSSORoles(sessionName, userAgent string) ([]SSORole, error) {
+	session, err := loadSSOSession(sessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	return listSSORoles(session, userAgent)
+}
+
+// listSSORoles lists every account and role pair that is available to the
+// given session.
+func listSSORoles(session *SSOSession, userAgent string) ([]SSORole, error) {
+	token, err := ssoAccessToken(session, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	client := newSSOClient(session, userAgent)
+
+	var roles []SSORole
+
+	// Page through the entire list of accounts.
+	accounts := sso.NewListAccountsPaginator(client, &sso.ListAccountsInput{
+		AccessToken: aws.String(token),
+	})
+	for accounts.HasMorePages() {
+		page, err := accounts.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, account := range page.AccountList {
+			// Page through the entire list of roles in each account.
+			accountRoles := sso.NewListAccountRolesPaginator(client, &sso.ListAccountRolesInput{
+				AccessToken: aws.String(token),
+				AccountId:   account.AccountId,
+			})
+			for accountRoles.HasMorePages() {
+				page, err := accountRoles.NextPage(context.Background())
+				if err != nil {
+					return nil, err
+				}
+
+				for _, role := range page.RoleList {
+					roles = append(roles, SSORole{
+						AccountID:   aws.ToString(account.AccountId),
+						AccountName: aws.ToString(account.AccountName),
+						RoleName:    aws.ToString(role.RoleName),
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].AccountName != roles[j].AccountName {
+			return roles[i].AccountName < roles[j].AccountName
+		}
+
+		return roles[i].RoleName < roles[j].RoleName
+	})
+
+	return roles, nil
+}
+
+// FromSSO retrieves credentials for the given account and role, using the
+// named sso-session, by calling SSO GetRoleCredentials. The account can be
+// given as either an ID or a name. If no role name is given, then the only
+// role available in the account is used.
+func FromSSO(sessionName, account, roleName, userAgent string) (*aws.Credentials, error) {
+	if account == "" {
+		return nil, errors.New("an account must be given when using an sso-session")
+	}
+
+	session, err := loadSSOSession(sessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	accountID := account
+
+	// Find the account ID and role name among the available roles if they
+	// cannot be used as given.
+	if !IsAccountID(account) || roleName == "" {
+		roles, err := listSSORoles(session, userAgent)
+		if err != nil {
+			return nil, err
+		}
+
+		role, err := findSSORole(roles, account, roleName)
+		if err != nil {
+			return nil, err
+		}
+
+		accountID, roleName = role.AccountID, role.RoleName
+	}
+
+	token, err := ssoAccessToken(session, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := newSSOClient(session, userAgent).GetRoleCredentials(context.Background(), &sso.GetRoleCredentialsInput{
+		AccessToken: aws.String(token),
+		AccountId:   aws.String(accountID),
+		RoleName:    aws.String(roleName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &aws.Credentials{
+		AccessKeyID:     aws.ToString(result.RoleCredentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(result.RoleCredentials.SecretAccessKey),
+		SessionToken:    aws.ToString(result.RoleCredentials.SessionToken),
+		CanExpire:       true,
+		Expires:         time.UnixMilli(result.RoleCredentials.Expiration),
+	}, nil
+}
+
+// findSSORole finds the single role matching the given account (ID or name)
+// and optional role name.
+func findSSORole(roles []SSORole, account, roleName string) (*SSORole, error) {
+	var (
+		matches   []SSORole
+		available []string
+	)
+
+	for _, role := range roles {
+		if role.AccountID != account && !strings.EqualFold(role.AccountName, account) {
+			continue
+		}
+
+		available = append(available, role.RoleName)
+
+		if roleName == "" || role.RoleName == roleName {
+			matches = append(matches, role)
+		}
+	}
+
+	switch {
+	case len(available) == 0:
+		return nil, fmt.Errorf("could not find account %q", account)
+	case len(matches) == 0:
+		return nil, fmt.Errorf("could not find role %q in account %q (available roles: %s)", roleName, account, strings.Join(available, ", "))
+	case len(matches) > 1:
+		return nil, fmt.Errorf("multiple roles available in account %q, choose one of: %s", account, strings.Join(available, ", "))
+	default:
+		return &matches[0], nil
+	}
+}
+
+// ssoAccessToken returns the cached access token for the given session,
+// refreshing it if needed.
+func ssoAccessToken(session *SSOSession, userAgent string) (string, error) {
+	filename, err := ssocreds.StandardCachedTokenFilepath(session.cacheKey())
+	if err != nil {
+		return "", err
+	}
+
+	token, err := ssocreds.NewSSOTokenProvider(newSSOOIDCClient(session, userAgent), filename).RetrieveBearerToken(context.Background())
+	if err != nil {
+		if !session.hasValidToken() {
+			return "", &SSOLoginError{Session: session, Err: err}
+		}
+
+		return "", err
+	}
+
+	return token.Value, nil
+}
+
+// newSSOOIDCClient returns an SSO OIDC client that makes calls in the region
+// of the given session.
+func newSSOOIDCClient(session *SSOSession, userAgent string) *ssooidc.Client {
+	return ssooidc.NewFromConfig(
+		aws.Config{Region: session.Region},
+		func(options *ssooidc.Options) {
+			options.APIOptions = append(options.APIOptions, setUserAgent(userAgent))
+		},
+	)
+}
+
+// newSSOClient returns an SSO client that makes calls in the region of the
+// given session.
+func newSSOClient(session *SSOSession, userAgent string) *sso.Client {
+	return sso.NewFromConfig(
+		aws.Config{Region: session.Region},
+		func(options *sso.Options) {
+			options.APIOptions = append(options.APIOptions, setUserAgent(userAgent))
+		},
+	)
+}
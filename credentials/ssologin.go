@@ -0,0 +1,251 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+// SSOLoginError is returned when credentials could not be retrieved because
+// the cached SSO access token for a session is missing or has expired.
+type SSOLoginError struct {
+	Session *SSOSession
+	Err     error
+}
+
+func (e *SSOLoginError) Error() string {
+	return "the SSO session has expired or is invalid: " + e.Err.Error()
+}
+
+func (e *SSOLoginError) Unwrap() error {
+	return e.Err
+}
+
+// ssoCachedToken is the format of the SSO access token cache files, typically
+// located in ~/.aws/sso/cache, as written by the AWS cli.
+type ssoCachedToken struct {
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	Region                string `json:"region,omitempty"`
+	StartURL              string `json:"startUrl,omitempty"`
+}
+
+// cacheKey returns the key used for naming the access token cache file. That
+// is the session name, or the start URL for legacy profiles.
+func (s *SSOSession) cacheKey() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return s.StartURL
+}
+
+// hasValidToken reports whether an unexpired access token is cached for the
+// session.
+func (s *SSOSession) hasValidToken() bool {
+	filename, err := ssocreds.StandardCachedTokenFilepath(s.cacheKey())
+	if err != nil {
+		return false
+	}
+
+	body, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+
+	var token ssoCachedToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return false
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt)
+	if err != nil {
+		return false
+	}
+
+	return token.AccessToken != "" && time.Now().Before(expiresAt)
+}
+
+// LoginSSO performs the SSO OIDC device authorization flow for the given
+// session, and writes the resulting access token to the cache. The given
+// prompt function is called with the verification URL and user code, which
+// the user must visit and confirm before the flow can complete.
+// See https://docs.aws.amazon.com/singlesignon/latest/OIDCAPIReference/Welcome.html.
+func LoginSSO(session *SSOSession, userAgent string, prompt func(verificationURL, userCode string) error) error {
+	ctx := context.Background()
+	client := newSSOOIDCClient(session, userAgent)
+
+	// Only sso-session tokens are scoped, and can be refreshed.
+	// See https://docs.aws.amazon.com/cli/latest/userguide/sso-configure-profile-token.html.
+	var scopes []string
+	if session.Name != "" {
+		scopes = []string{"sso:account:access"}
+	}
+
+	// Register this tool as a public OIDC client.
+	registration, err := client.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String("aws-console"),
+		ClientType: aws.String("public"),
+		Scopes:     scopes,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Start the device authorization flow.
+	authorization, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     registration.ClientId,
+		ClientSecret: registration.ClientSecret,
+		StartUrl:     aws.String(session.StartURL),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := prompt(aws.ToString(authorization.VerificationUriComplete), aws.ToString(authorization.UserCode)); err != nil {
+		return err
+	}
+
+	// Poll for an access token until the user has confirmed the
+	// authorization, or the device code has expired.
+	token, err := pollSSOToken(ctx, client, registration, authorization)
+	if err != nil {
+		return err
+	}
+
+	cached := ssoCachedToken{
+		AccessToken: aws.ToString(token.AccessToken),
+		ExpiresAt:   time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).UTC().Format(time.RFC3339),
+		Region:      session.Region,
+		StartURL:    session.StartURL,
+	}
+
+	// Store the client registration alongside the refresh token, so that
+	// the token can later be refreshed.
+	if session.Name != "" {
+		cached.RefreshToken = aws.ToString(token.RefreshToken)
+		cached.ClientID = aws.ToString(registration.ClientId)
+		cached.ClientSecret = aws.ToString(registration.ClientSecret)
+		cached.RegistrationExpiresAt = time.Unix(registration.ClientSecretExpiresAt, 0).UTC().Format(time.RFC3339)
+	}
+
+	return writeSSOToken(session, cached)
+}
+
+// pollSSOToken repeatedly calls SSO OIDC CreateToken until the pending device
+// authorization is either confirmed or has expired.
+func pollSSOToken(ctx context.Context, client *ssooidc.Client, registration *ssooidc.RegisterClientOutput, authorization *ssooidc.StartDeviceAuthorizationOutput) (*ssooidc.CreateTokenOutput, error) {
+	// The amount of time to add to the polling interval when asked to slow
+	// down, as specified by RFC 8628.
+	// See https://datatracker.ietf.org/doc/html/rfc8628#section-3.5.
+	const slowDown = 5 * time.Second
+
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval == 0 {
+		interval = slowDown
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(authorization.ExpiresIn)*time.Second)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("timed out waiting for SSO authorization")
+		case <-time.After(interval):
+		}
+
+		token, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     registration.ClientId,
+			ClientSecret: registration.ClientSecret,
+			DeviceCode:   authorization.DeviceCode,
+			GrantType:    aws.String("urn:ietf:params:oauth:grant-type:device_code"),
+		})
+
+		var (
+			pendingErr  *types.AuthorizationPendingException
+			slowDownErr *types.SlowDownException
+		)
+
+		switch {
+		case errors.As(err, &pendingErr):
+			// The user has not yet confirmed the authorization.
+			continue
+		case errors.As(err, &slowDownErr):
+			// Polling is happening too frequently.
+			interval += slowDown
+
+			continue
+		case err != nil:
+			return nil, err
+		default:
+			return token, nil
+		}
+	}
+}
+
+// writeSSOToken writes the given access token to the cache file for the given
+// session.
+func writeSSOToken(session *SSOSession, token ssoCachedToken) error {
+	filename, err := ssocreds.StandardCachedTokenFilepath(session.cacheKey())
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o700); err != nil { //nolint:mnd
+		return err
+	}
+
+	return os.WriteFile(filename, body, 0o600) //nolint:mnd
+}
+
+// ssoSessionForProfile returns the IAM Identity Center session used by the
+// named profile, either directly or by way of a source profile. Returns nil if
+// the profile does not use IAM Identity Center.
+func ssoSessionForProfile(ctx context.Context, profile string) *SSOSession {
+	shared, err := loadSharedConfigProfile(ctx, profile)
+	if err != nil {
+		return nil
+	}
+
+	// Walk the chain of source profiles looking for an SSO configuration.
+	for cfg := &shared; cfg != nil; cfg = cfg.Source {
+		switch {
+		case cfg.SSOSession != nil:
+			return &SSOSession{
+				Name:     cfg.SSOSession.Name,
+				Region:   cfg.SSOSession.SSORegion,
+				StartURL: cfg.SSOSession.SSOStartURL,
+			}
+		case cfg.SSOStartURL != "":
+			return &SSOSession{
+				Region:   cfg.SSORegion,
+				StartURL: cfg.SSOStartURL,
+			}
+		}
+	}
+
+	return nil
+}
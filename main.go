@@ -7,6 +7,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -16,6 +17,13 @@ import (
 func main() {
 	if err := cmd.Command().Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, "aws-console:", err)
+
+		// Exit with the code for the kind of failure, if there is one.
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+
 		os.Exit(1)
 	}
 }